@@ -0,0 +1,574 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/service"
+)
+
+func newAddCommand() *command {
+	c := newCommand("add", `按分享文件批量添加存储
+
+读取 config.yaml 中已启用类型对应的文件, 逐条创建存储:
+  aliyunshare  阿里云盘分享
+  aliyunopen   阿里云盘 Open 网盘中的文件夹
+  pikpakshare  PikPak 分享
+  pikpakdrive  PikPak 账户网盘中的文件夹
+  onedriveapp  OneDrive 应用
+  protocol     WebDAV、S3、SFTP、FTP、SMB 协议存储
+  local        OpenList 所在主机上的本地目录
+  alias        合并已有挂载路径的别名 (最后添加, 可以引用本次新建的存储)
+挂载路径按 config.yaml 中的 mount_path 规则生成, 已存在的路径按冲突处理;
+驱动、share_id、root_folder_id 相同的分享视为重复, 按 duplicate 策略处理;
+按 verify 策略列出新建存储的根目录, 报告、禁用或删除为空或失败的挂载;
+分享文件不存在时生成模板后退出`, "",
+		"openlist_batch add",
+		"openlist_batch add -profile prod",
+		"openlist_batch add -all-profiles",
+	)
+	allProfiles := c.flags.Bool("all-profiles", false, "对所有命名实例执行批量添加, 并汇总各实例结果")
+	policy := addPolicyFlags(c.flags)
+
+	c.run = func(args []string) error {
+		if *allProfiles {
+			return addAllProfiles(newLoader(), policy)
+		}
+
+		svc, cfg, loader, err := openService()
+		if err != nil {
+			return err
+		}
+		defer svc.Close()
+		if err := policy.apply(cfg); err != nil {
+			return err
+		}
+
+		if ok, err := ensureShareFiles(loader, cfg); !ok {
+			return err
+		}
+
+		result, err := addStorages(svc, cfg, loader)
+		logResult("批量操作完成", result)
+		return err
+	}
+	return c
+}
+
+// addAllProfiles 对每个命名实例执行批量添加
+func addAllProfiles(loader *config.Loader, policy policyFlags) error {
+	names, err := loader.ProfileNames()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("config.yaml 中没有配置 profiles")
+	}
+
+	type report struct {
+		name   string
+		result service.Result
+		err    error
+	}
+	reports := make([]report, 0, len(names))
+
+	for _, name := range names {
+		log.Printf("===== 实例 %s =====", name)
+		result, err := addProfile(loader, name, policy)
+		reports = append(reports, report{name, result, err})
+	}
+
+	log.Println("===== 各实例结果 =====")
+	failed := false
+	for _, r := range reports {
+		if r.err != nil {
+			failed = true
+			log.Printf("%s: 失败: %v", r.name, r.err)
+			continue
+		}
+		if r.result.Failed > 0 {
+			failed = true
+		}
+		logResult(r.name, r.result)
+	}
+	if failed {
+		return fmt.Errorf("部分实例未全部成功")
+	}
+	return nil
+}
+
+// addProfile 对单个实例执行批量添加
+func addProfile(loader *config.Loader, name string, policy policyFlags) (service.Result, error) {
+	cfg, err := loadProfile(loader, name)
+	if err != nil {
+		return service.Result{}, err
+	}
+	if err := policy.apply(cfg); err != nil {
+		return service.Result{}, err
+	}
+	ok, err := ensureShareFiles(loader, cfg)
+	if err != nil {
+		return service.Result{}, err
+	}
+	if !ok {
+		return service.Result{}, fmt.Errorf("分享文件不存在, 已生成模板")
+	}
+
+	svc, err := connect(cfg, loader)
+	if err != nil {
+		return service.Result{}, err
+	}
+	defer svc.Close()
+
+	return addStorages(svc, cfg, loader)
+}
+
+// addStorages 添加所有已启用的分享, 挂载路径冲突按 fail 处理时中止
+func addStorages(svc *service.BatchService, cfg *config.Config, loader *config.Loader) (service.Result, error) {
+	var result service.Result
+
+	for _, src := range shareSources(cfg) {
+		shares, err := loader.LoadShareList(src.file)
+		if err != nil {
+			log.Printf("加载%s失败: %v", src.label, err)
+			continue
+		}
+		log.Printf("正在添加%s...", src.label)
+		added, err := svc.BatchAddShares(src.provider, shares)
+		if err != nil {
+			return result, err
+		}
+		result.Merge(added)
+	}
+
+	return result, nil
+}
+
+func newSyncCommand() *command {
+	c := newCommand("sync", `让 OpenList 与分享文件保持一致
+
+缺少的存储会创建并按 verify 策略验证, 附加信息 (分享链接、提取码、凭据等) 变化的存储会更新;
+指定 -prune 时, 删除分享文件中出现的分类下、但文件里已不存在的同类型存储;
+protected_paths 中的存储不会被更新或删除, 除非指定 -force`, "",
+		"openlist_batch sync",
+		"openlist_batch sync -prune",
+	)
+	prune := c.flags.Bool("prune", false, "删除分享文件中已移除的存储")
+	force := addForceFlag(c.flags)
+	policy := addPolicyFlags(c.flags)
+
+	c.run = func(args []string) error {
+		svc, cfg, loader, err := openService()
+		if err != nil {
+			return err
+		}
+		defer svc.Close()
+		svc.SetForce(*force)
+		if err := policy.apply(cfg); err != nil {
+			return err
+		}
+
+		if ok, err := ensureShareFiles(loader, cfg); !ok {
+			return err
+		}
+
+		var total service.SyncResult
+		for _, src := range shareSources(cfg) {
+			shares, err := loader.LoadShareList(src.file)
+			if err != nil {
+				return fmt.Errorf("加载%s失败: %w", src.label, err)
+			}
+			log.Printf("正在同步%s...", src.label)
+			result, err := svc.SyncShares(src.provider, shares, *prune)
+			if err != nil {
+				return err
+			}
+			total.Merge(result)
+		}
+
+		log.Printf("同步完成: 创建 %d, 更新 %d, 未变化 %d, 删除 %d, 跳过 %d, 失败 %d",
+			total.Created, total.Updated, total.Unchanged, total.Deleted, total.Skipped, total.Failed)
+		logVerify(total.Verify)
+		return nil
+	}
+	return c
+}
+
+func newImportCommand() *command {
+	c := newCommand("import", `从分享文件导入存储
+
+与 add 相同, 但分享文件由参数指定, 不要求在 config.yaml 中启用,
+可用于导入 export 导出的文件`, "<文件>",
+		"openlist_batch import -type pikpakshare pikpak_share_export.yaml",
+		"openlist_batch import -type aliyunshare 'shares/*.yaml'",
+	)
+	kind := c.flags.String("type", "", "分享类型: aliyunshare, aliyunopen, pikpakshare, pikpakdrive, onedriveapp, protocol, local, alias")
+	policy := addPolicyFlags(c.flags)
+
+	c.run = func(args []string) error {
+		if len(args) != 1 {
+			c.usage()
+			return fmt.Errorf("需要指定一个分享文件")
+		}
+
+		svc, cfg, loader, err := openService()
+		if err != nil {
+			return err
+		}
+		defer svc.Close()
+		if err := policy.apply(cfg); err != nil {
+			return err
+		}
+
+		src, err := shareSourceOf(cfg, *kind)
+		if err != nil {
+			return err
+		}
+
+		shares, err := loader.LoadShareList(argPath(loader, args[0], ""))
+		if err != nil {
+			return err
+		}
+
+		return addShares(svc, src, shares, "导入")
+	}
+	return c
+}
+
+func newUsersCommand() *command {
+	c := newCommand("users", `从租户用户列表生成 OneDrive 挂载列表
+
+读取 Graph /users 的 JSON 响应或 Azure AD / Microsoft 365 管理中心导出的用户 CSV,
+为租户下已启用且分配了许可证的用户生成列表形式的 OneDrive 条目, 写入 -o 指定的文件;
+-include 和 -exclude 为逗号分隔的邮箱通配符 (不区分大小写), 先按 -include 筛选再排除;
+邮箱 @ 前的部分相同的用户生成的名称重复, 只保留第一个.
+指定 -add 时随后按 add 的规则创建这些存储`, "<用户列表>",
+		"openlist_batch users -tenant contoso -category 员工 users.json",
+		"openlist_batch users -tenant 1 -category 员工 -exclude 'admin*,svc-*' -add users.csv",
+	)
+	tenantRef := c.flags.String("tenant", "", "租户名称、id、序号、tenant_id 或 client_id")
+	category := c.flags.String("category", "", "条目所在的分类, 默认为租户名称或序号")
+	include := c.flags.String("include", "", "只包含匹配的邮箱, 逗号分隔的通配符")
+	exclude := c.flags.String("exclude", "", "排除匹配的邮箱, 逗号分隔的通配符")
+	dir := c.flags.String("path", "", "挂载的 OneDrive 文件夹, 默认为根目录")
+	output := c.flags.String("o", "", "输出文件路径, 默认为工作目录下的 onedrive_<分类>.yaml")
+	add := c.flags.Bool("add", false, "生成后创建存储")
+	policy := addPolicyFlags(c.flags)
+
+	c.run = func(args []string) error {
+		if len(args) != 1 {
+			c.usage()
+			return fmt.Errorf("需要指定一个用户列表文件")
+		}
+		if *tenantRef == "" {
+			return fmt.Errorf("需要用 -tenant 指定租户")
+		}
+
+		loader := newLoader()
+		cfg, err := loadConfig(loader)
+		if err != nil {
+			return err
+		}
+		tenant, err := cfg.OneDriveApp.FindTenant(*tenantRef)
+		if err != nil {
+			return err
+		}
+		ref := cfg.OneDriveApp.TenantRef(tenant)
+		if *category == "" {
+			*category = ref
+		}
+
+		users, err := loader.LoadDirectoryUsers(argPath(loader, args[0], ""))
+		if err != nil {
+			return err
+		}
+
+		includes, excludes := splitPatterns(*include), splitPatterns(*exclude)
+
+		var entries []string
+		names := make(map[string]string)
+		skipped := 0
+		for _, user := range users {
+			switch {
+			case !user.Enabled || !user.Licensed:
+				skipped++
+				continue
+			case includes != nil && !config.MatchUserPatterns(includes, user.Email):
+				skipped++
+				continue
+			case config.MatchUserPatterns(excludes, user.Email):
+				skipped++
+				continue
+			}
+
+			entry := ref + ":" + user.Email
+			if *dir != "" {
+				entry += ":" + *dir
+			}
+			name, err := config.OneDriveListName(entry)
+			if err != nil {
+				log.Printf("跳过 %s: %v", user.Email, err)
+				skipped++
+				continue
+			}
+			if other, ok := names[name]; ok {
+				log.Printf("跳过 %s: 名称 %s 与 %s 重复", user.Email, name, other)
+				skipped++
+				continue
+			}
+			names[name] = user.Email
+			entries = append(entries, entry)
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("没有符合条件的用户 (共 %d 个)", len(users))
+		}
+
+		list := config.OneDriveList{*category: entries}
+		outputFile := argPath(loader, *output, "onedrive_"+*category+".yaml")
+		if err := loader.SaveOneDriveList(outputFile, list); err != nil {
+			return fmt.Errorf("保存挂载列表失败: %w", err)
+		}
+		log.Printf("已生成 %d 个条目到 %s, 跳过 %d 个用户", len(entries), outputFile, skipped)
+
+		if !*add {
+			return nil
+		}
+		if err := policy.apply(cfg); err != nil {
+			return err
+		}
+		shares, err := list.ShareList()
+		if err != nil {
+			return err
+		}
+		src, err := shareSourceOf(cfg, "onedriveapp")
+		if err != nil {
+			return err
+		}
+		return connectAndAdd(cfg, loader, src, shares)
+	}
+	return c
+}
+
+func newScanCommand() *command {
+	c := newCommand("scan", `从目录生成本地存储挂载列表
+
+为目录下的每个子目录生成一个本地存储条目, 写入 -o 指定的文件;
+-include 和 -exclude 为逗号分隔的子目录名通配符, 先按 -include 筛选再排除, 默认跳过 . 开头的目录;
+OpenList 运行在容器或其他主机上时, 用 -root 指定该目录在 OpenList 中看到的路径.
+指定 -add 时随后按 add 的规则创建这些存储`, "<目录>",
+		"openlist_batch scan /mnt/media",
+		"openlist_batch scan -category 媒体 -exclude 'tmp*,@*' -root /media -add /mnt/media",
+	)
+	category := c.flags.String("category", "", "条目所在的分类, 默认为目录名")
+	include := c.flags.String("include", "", "只包含匹配的子目录, 逗号分隔的通配符")
+	exclude := c.flags.String("exclude", "", "排除匹配的子目录, 逗号分隔的通配符")
+	hidden := c.flags.Bool("hidden", false, "包含 . 开头的子目录")
+	root := c.flags.String("root", "", "该目录在 OpenList 所在主机上的路径, 默认与扫描的目录相同")
+	output := c.flags.String("o", "", "输出文件路径, 默认为工作目录下的 local_<分类>.yaml")
+	add := c.flags.Bool("add", false, "生成后创建存储")
+	policy := addPolicyFlags(c.flags)
+
+	c.run = func(args []string) error {
+		if len(args) != 1 {
+			c.usage()
+			return fmt.Errorf("需要指定一个目录")
+		}
+
+		loader := newLoader()
+		cfg, err := loadConfig(loader)
+		if err != nil {
+			return err
+		}
+
+		dir, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+		if *root == "" {
+			*root = filepath.ToSlash(dir)
+		}
+		if *category == "" {
+			*category = filepath.Base(dir)
+		}
+
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("读取目录失败: %w", err)
+		}
+
+		includes, excludes := splitPatterns(*include), splitPatterns(*exclude)
+
+		entries := make(map[string]string)
+		skipped := 0
+		for _, f := range files {
+			name := f.Name()
+			if !isDir(dir, f) {
+				continue
+			}
+			switch {
+			case !*hidden && strings.HasPrefix(name, "."):
+				skipped++
+				continue
+			case includes != nil && !matchNames(includes, name):
+				skipped++
+				continue
+			case matchNames(excludes, name):
+				skipped++
+				continue
+			}
+			entries[name] = hostPath(*root, name)
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("%s 下没有符合条件的子目录", dir)
+		}
+
+		list := config.ShareList{*category: entries}
+		outputFile := argPath(loader, *output, "local_"+*category+".yaml")
+		if err := loader.SaveShareList(outputFile, list); err != nil {
+			return fmt.Errorf("保存挂载列表失败: %w", err)
+		}
+		log.Printf("已生成 %d 个条目到 %s, 跳过 %d 个子目录", len(entries), outputFile, skipped)
+
+		if !*add {
+			return nil
+		}
+		if err := policy.apply(cfg); err != nil {
+			return err
+		}
+		src, err := shareSourceOf(cfg, "local")
+		if err != nil {
+			return err
+		}
+		return connectAndAdd(cfg, loader, src, list)
+	}
+	return c
+}
+
+func newAggregateCommand() *command {
+	c := newCommand("aggregate", `把名称相同的已有存储合并为别名
+
+按挂载路径的最后一段给未禁用的存储分组, 比较前删除 alias.aggregate.ignore 匹配的部分,
+并忽略全角半角、大小写、空白和标点; 一组至少两个存储且来自 min_drivers 种驱动时
+生成一个别名, 挂载路径按 mount_path 规则由分类和名称生成.
+默认只列出计划, 指定 -apply 时创建新的别名; -o 把计划写成 alias.yaml 格式的文件,
+可在 alias.file 中引用后用 sync 维护`, "",
+		"openlist_batch aggregate",
+		"openlist_batch aggregate -filter 'path~/电视剧/*' -name '{name} ({count})'",
+		"openlist_batch aggregate -o alias_auto.yaml -apply",
+	)
+	filterExpr := c.flags.String("filter", "", "只合并满足条件的存储, 语法同 list")
+	category := c.flags.String("category", "", "别名所在的分类, 默认使用 alias.aggregate.category")
+	name := c.flags.String("name", "", "别名名称模板, 可用变量 {name} {category} {count}, 默认使用 alias.aggregate.name")
+	minDrivers := c.flags.Int("min", 0, "一组存储至少来自几种驱动, 默认使用 alias.aggregate.min_drivers")
+	output := c.flags.String("o", "", "把计划写入别名挂载列表文件")
+	apply := c.flags.Bool("apply", false, "创建新的别名")
+
+	c.run = func(args []string) error {
+		filter, err := service.ParseFilter(*filterExpr)
+		if err != nil {
+			return err
+		}
+
+		svc, cfg, loader, err := openService()
+		if err != nil {
+			return err
+		}
+		defer svc.Close()
+
+		rules := &cfg.Alias.Aggregate
+		if *category != "" {
+			rules.Category = *category
+		}
+		if *name != "" {
+			rules.Name = *name
+		}
+		if *minDrivers > 0 {
+			rules.MinDrivers = *minDrivers
+		}
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+
+		list, err := svc.GetStorageList()
+		if err != nil {
+			return fmt.Errorf("获取存储列表失败: %w", err)
+		}
+		groups, err := svc.PlanAliases(list.Content, filter, *rules)
+		if err != nil {
+			return err
+		}
+		if len(groups) == 0 {
+			log.Println("没有可以合并的存储")
+			return nil
+		}
+
+		counts := make(map[string]int)
+		for _, g := range groups {
+			counts[g.Status]++
+			status := g.Status
+			if g.Reason != "" {
+				status += ": " + g.Reason
+			}
+			fmt.Printf("%s  [%s]\n", g.MountPath, status)
+			for _, item := range g.Members {
+				fmt.Printf("  %s (%s)\n", item.MountPath, item.Driver)
+			}
+		}
+		log.Printf("共 %d 组: %s %d, %s %d, %s %d", len(groups),
+			service.AliasNew, counts[service.AliasNew],
+			service.AliasPresent, counts[service.AliasPresent],
+			service.AliasConflict, counts[service.AliasConflict])
+
+		if *output != "" {
+			outputFile := argPath(loader, *output, "")
+			if err := loader.SaveAliasList(outputFile, service.AliasListOf(groups)); err != nil {
+				return fmt.Errorf("保存别名列表失败: %w", err)
+			}
+			log.Printf("已写入 %s", outputFile)
+		}
+
+		if !*apply {
+			if counts[service.AliasNew] > 0 {
+				log.Println("使用 -apply 创建新的别名")
+			}
+			return nil
+		}
+		result := svc.CreateAliases(groups)
+		log.Printf("添加完成: 成功 %d, 跳过 %d, 失败 %d", result.Added, result.Skipped, result.Failed)
+		logVerify(result.Verify)
+		return nil
+	}
+	return c
+}
+
+// isDir 检查目录项是否为目录, 指向目录的符号链接也视为目录
+func isDir(dir string, f os.DirEntry) bool {
+	if f.Type()&os.ModeSymlink == 0 {
+		return f.IsDir()
+	}
+	info, err := os.Stat(filepath.Join(dir, f.Name()))
+	return err == nil && info.IsDir()
+}
+
+// matchNames 检查名称是否匹配任意一个通配符
+func matchNames(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.TrimSpace(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hostPath 拼接 OpenList 所在主机上的路径, root 为 Windows 路径时使用反斜杠
+func hostPath(root, name string) string {
+	if strings.Contains(root, `\`) && !strings.Contains(root, "/") {
+		return strings.TrimRight(root, `\`) + `\` + name
+	}
+	return path.Join(root, name)
+}
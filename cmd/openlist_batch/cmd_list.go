@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+	"github.com/yzbtdiy/openlist_batch/internal/service"
+	"gopkg.in/yaml.v3"
+)
+
+// column 列表输出的列
+type column struct {
+	name  string
+	value func(model.StorageItem) any
+}
+
+// listColumns 支持的列, 顺序即默认显示顺序
+var listColumns = []column{
+	{"id", func(i model.StorageItem) any { return i.Id }},
+	{"driver", func(i model.StorageItem) any { return i.Driver }},
+	{"status", func(i model.StorageItem) any { return i.Status }},
+	{"disabled", func(i model.StorageItem) any { return i.Disabled }},
+	{"order", func(i model.StorageItem) any { return i.Order }},
+	{"path", func(i model.StorageItem) any { return i.MountPath }},
+	{"remark", func(i model.StorageItem) any { return i.Remark }},
+	{"modified", func(i model.StorageItem) any { return i.Modified.Format(time.DateTime) }},
+	{"cache", func(i model.StorageItem) any { return i.CacheExpiration }},
+	{"webdav", func(i model.StorageItem) any { return i.WebdavPolicy }},
+}
+
+// defaultColumns 未指定 -columns 时表格显示的列
+const defaultColumns = "id,driver,status,disabled,path"
+
+// listSorts 支持的排序字段
+var listSorts = map[string]func(a, b model.StorageItem) bool{
+	"id":       func(a, b model.StorageItem) bool { return a.Id < b.Id },
+	"order":    func(a, b model.StorageItem) bool { return a.Order < b.Order },
+	"path":     func(a, b model.StorageItem) bool { return a.MountPath < b.MountPath },
+	"modified": func(a, b model.StorageItem) bool { return a.Modified.Before(b.Modified) },
+	"driver":   func(a, b model.StorageItem) bool { return a.Driver < b.Driver },
+}
+
+func newListCommand() *command {
+	c := newCommand("list", `列出 OpenList 中的存储
+
+-filter 语法: 以空格分隔的若干条件, 全部满足才显示
+  字段:   id, driver, path, status, disabled, remark, order
+  运算符: = 等于, != 不等于, ~ 通配匹配, !~ 通配不匹配, > >= < <= 数值比较
+  多个候选值用 | 分隔, 含空格的值用双引号包裹`, "",
+		"openlist_batch list",
+		"openlist_batch list -filter 'driver=PikPakShare status!=work'",
+		"openlist_batch list -filter 'path~/电影/*' -sort -modified",
+		"openlist_batch list -format json -columns id,path,remark",
+	)
+	format := c.flags.String("format", "table", "输出格式: table, json, yaml")
+	columns := c.flags.String("columns", "", "显示的列, 逗号分隔, 可选: "+columnNames()+" (表格默认 "+defaultColumns+", json/yaml 默认全部)")
+	sortBy := c.flags.String("sort", "id", "排序字段: id, order, path, modified, driver, 前缀 - 表示倒序")
+	filterExpr := c.flags.String("filter", "", "筛选表达式")
+
+	c.run = func(args []string) error {
+		filter, err := service.ParseFilter(*filterExpr)
+		if err != nil {
+			return err
+		}
+
+		cols := *columns
+		if cols == "" && *format == "table" {
+			cols = defaultColumns
+		}
+		selected, err := selectColumns(cols)
+		if err != nil {
+			return err
+		}
+
+		svc, _, _, err := openService()
+		if err != nil {
+			return err
+		}
+		defer svc.Close()
+
+		list, err := svc.GetStorageList()
+		if err != nil {
+			return fmt.Errorf("获取存储列表失败: %w", err)
+		}
+
+		items := filter.Apply(list.Content)
+		if err := sortItems(items, *sortBy); err != nil {
+			return err
+		}
+
+		return printItems(os.Stdout, *format, selected, items)
+	}
+	return c
+}
+
+// columnNames 返回所有列名
+func columnNames() string {
+	names := make([]string, len(listColumns))
+	for i, col := range listColumns {
+		names[i] = col.name
+	}
+	return strings.Join(names, ",")
+}
+
+// selectColumns 按逗号分隔的列名选择列, 为空时返回全部
+func selectColumns(spec string) ([]column, error) {
+	if spec == "" {
+		return listColumns, nil
+	}
+
+	var selected []column
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		found := false
+		for _, col := range listColumns {
+			if col.name == name {
+				selected = append(selected, col)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("未知的列: %s, 可选值: %s", name, columnNames())
+		}
+	}
+	return selected, nil
+}
+
+// sortItems 按字段排序, 前缀 - 表示倒序
+func sortItems(items []model.StorageItem, spec string) error {
+	field, desc := strings.CutPrefix(spec, "-")
+	less, ok := listSorts[field]
+	if !ok {
+		return fmt.Errorf("未知的排序字段: %s", field)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if desc {
+			return less(items[j], items[i])
+		}
+		return less(items[i], items[j])
+	})
+	return nil
+}
+
+// printItems 按格式输出存储列表
+func printItems(w io.Writer, format string, cols []column, items []model.StorageItem) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		headers := make([]string, len(cols))
+		for i, col := range cols {
+			headers[i] = strings.ToUpper(col.name)
+		}
+		fmt.Fprintln(tw, strings.Join(headers, "\t"))
+		for _, item := range items {
+			values := make([]string, len(cols))
+			for i, col := range cols {
+				values[i] = fmt.Sprint(col.value(item))
+			}
+			fmt.Fprintln(tw, strings.Join(values, "\t"))
+		}
+		return tw.Flush()
+
+	case "json":
+		return writeJSON(w, cols, items)
+
+	case "yaml":
+		rows := make([]yaml.Node, 0, len(items))
+		for _, item := range items {
+			row := yaml.Node{Kind: yaml.MappingNode}
+			for _, col := range cols {
+				var val yaml.Node
+				if err := val.Encode(col.value(item)); err != nil {
+					return err
+				}
+				row.Content = append(row.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: col.name}, &val)
+			}
+			rows = append(rows, row)
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(rows); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("未知的输出格式: %s, 可选值: table, json, yaml", format)
+}
+
+// writeJSON 按列顺序输出 JSON 数组
+func writeJSON(w io.Writer, cols []column, items []model.StorageItem) error {
+	var b strings.Builder
+	b.WriteString("[")
+	for i, item := range items {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  {")
+		for j, col := range cols {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			value, err := json.Marshal(col.value(item))
+			if err != nil {
+				return err
+			}
+			b.WriteString(strconv.Quote(col.name) + ": " + string(value))
+		}
+		b.WriteString("}")
+	}
+	if len(items) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func newInspectCommand() *command {
+	c := newCommand("inspect", `查看单个存储的详细信息
+
+按 ID 或挂载路径查找存储, 输出通用字段与解析后的附加信息 (addition),
+refresh_token、client_secret、password 等敏感字段默认打码`, "<ID|挂载路径>",
+		"openlist_batch inspect 12",
+		"openlist_batch inspect /电影/阿飞正传",
+		"openlist_batch inspect -reveal 12",
+	)
+	reveal := c.flags.Bool("reveal", false, "显示敏感字段原文")
+
+	c.run = func(args []string) error {
+		if len(args) != 1 {
+			c.usage()
+			return fmt.Errorf("需要指定一个存储 ID 或挂载路径")
+		}
+
+		svc, _, _, err := openService()
+		if err != nil {
+			return err
+		}
+		defer svc.Close()
+
+		item, err := svc.FindStorage(args[0])
+		if err != nil {
+			return err
+		}
+
+		addition, err := service.DecodeAddition(item.Addition)
+		if err != nil {
+			return err
+		}
+		if !*reveal {
+			addition = service.MaskSecrets(addition)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, col := range listColumns {
+			fmt.Fprintf(tw, "%s:\t%v\n", col.name, col.value(*item))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(addition, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("addition:\n%s\n", data)
+		return nil
+	}
+	return c
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/provider"
+)
+
+func newInitCommand() *command {
+	c := newCommand("init", `生成配置文件模板
+
+在工作目录生成 config.yaml, 指定 -shares 时同时生成各类分享文件模板; 已存在的文件不会覆盖`, "",
+		"openlist_batch init",
+		"openlist_batch init -shares",
+		"openlist_batch -workdir ~/.config/openlist_batch init",
+	)
+	shares := c.flags.Bool("shares", false, "同时生成分享文件模板")
+
+	c.run = func(args []string) error {
+		loader := newLoader()
+
+		if loader.FileExists(loader.ConfigPath()) {
+			log.Printf("配置文件 %s 已存在", loader.ConfigPath())
+		} else {
+			if err := loader.GenerateTemplateAs(config.ConfigFile, loader.ConfigPath()); err != nil {
+				return fmt.Errorf("生成配置文件失败: %w", err)
+			}
+			log.Printf("已生成 %s", loader.ConfigPath())
+		}
+
+		if !*shares {
+			return nil
+		}
+
+		// 配置文件已有时使用其中的分享文件路径
+		cfg := &config.Config{}
+		if loaded, err := loader.LoadProfile(options.profile); err == nil {
+			cfg = loaded
+		}
+		for _, kind := range shareKinds {
+			src, _ := shareSourceOf(cfg, kind)
+			if loader.FileExists(src.file) {
+				log.Printf("%s文件 %s 已存在", src.label, src.file)
+				continue
+			}
+			if err := loader.GenerateTemplateAs(src.template, src.file); err != nil {
+				return fmt.Errorf("生成%s文件失败: %w", src.label, err)
+			}
+			log.Printf("已生成 %s", loader.Path(src.file))
+		}
+		return nil
+	}
+	return c
+}
+
+func newCheckCommand() *command {
+	c := newCommand("check", `检查配置、分享文件与 OpenList 连接
+
+验证 config.yaml, 逐条解析已启用的分享文件, 并检查 token 是否可用以及
+别名引用的路径是否在已有存储下; 有任何问题时以非零状态退出`, "",
+		"openlist_batch check",
+		"openlist_batch check -profile prod",
+	)
+	offline := c.flags.Bool("offline", false, "不连接 OpenList, 只检查本地文件")
+
+	c.run = func(args []string) error {
+		loader := newLoader()
+		cfg, err := loadConfig(loader)
+		if err != nil {
+			return err
+		}
+		log.Printf("配置文件 %s 验证通过", loader.ConfigPath())
+
+		problems := 0
+		// 引用已有存储的条目 (别名) 在连接正常时检查引用的路径
+		var mounts []string
+		listed := false
+		if !*offline {
+			svc, err := connect(cfg, loader)
+			if err != nil {
+				log.Printf("连接 OpenList 失败: %v", err)
+				problems++
+			} else {
+				log.Printf("OpenList %s 连接正常", cfg.URL)
+				if list, err := svc.GetStorageList(); err != nil {
+					log.Printf("获取存储列表失败: %v", err)
+					problems++
+				} else {
+					for _, item := range list.Content {
+						mounts = append(mounts, item.MountPath)
+					}
+					listed = true
+				}
+				svc.Close()
+			}
+		}
+
+		for _, src := range shareSources(cfg) {
+			shares, err := loader.LoadShareList(src.file)
+			if err != nil {
+				log.Printf("%s: %v", src.label, err)
+				problems++
+				continue
+			}
+			checker, _ := src.provider.(provider.MountChecker)
+
+			count := 0
+			for _, category := range sortedKeys(shares) {
+				for _, name := range sortedKeys(shares[category]) {
+					count++
+					value := shares[category][name]
+					if err := checkEntry(src.provider, "/"+category+"/"+name, value); err != nil {
+						log.Printf("%s: %s/%s: %v", src.label, category, name, err)
+						problems++
+						continue
+					}
+					if checker == nil || !listed {
+						continue
+					}
+					if err := checker.CheckMounts(value, mounts); err != nil {
+						log.Printf("%s: %s/%s: %v", src.label, category, name, err)
+						problems++
+					}
+				}
+			}
+			log.Printf("%s: 共 %d 条", src.label, count)
+		}
+
+		if problems > 0 {
+			return fmt.Errorf("发现 %d 个问题", problems)
+		}
+		log.Println("检查通过")
+		return nil
+	}
+	return c
+}
+
+// checkEntry 检查条目能否构建挂载请求; 以路径指定文件夹的条目要连接 OpenList
+// 才能解析, 只检查能否构建挂载网盘根目录的请求
+func checkEntry(p provider.Provider, mountPath, value string) error {
+	if fp, ok := p.(provider.FolderPathProvider); ok {
+		if _, dir, err := fp.FolderPath(value); err != nil || dir != "" {
+			return err
+		}
+	}
+	_, err := p.BuildRequest(mountPath, value)
+	return err
+}
+
+func newValidateCommand() *command {
+	c := newCommand("validate", `静态检查分享文件
+
+不连接 OpenList, 按各提供商的规则逐条检查分享文件: 链接域名与路径、分享 ID 与
+文件夹 ID 格式、提取码长度、OneDrive 租户序号与邮箱, 以及重复的键和非字符串的值;
+问题按 文件:行号: 分类/名称: 原因 输出, 有任何问题时以非零状态退出.
+未指定文件时检查 config.yaml 中已启用的分享文件, 指定文件时需要 -type`, "[文件...]",
+		"openlist_batch validate",
+		"openlist_batch validate -type aliyunshare 'shares/*.yaml'",
+	)
+	kind := c.flags.String("type", "", "分享类型: aliyunshare, aliyunopen, pikpakshare, pikpakdrive, onedriveapp, protocol, local, alias")
+
+	c.run = func(args []string) error {
+		loader := newLoader()
+		cfg, err := loadConfig(loader)
+		if err != nil {
+			return err
+		}
+
+		type target struct {
+			src  shareSource
+			file string
+		}
+		var targets []target
+		switch {
+		case len(args) > 0:
+			if *kind == "" {
+				c.usage()
+				return fmt.Errorf("指定文件时需要 -type")
+			}
+			src, err := shareSourceOf(cfg, *kind)
+			if err != nil {
+				return err
+			}
+			for _, arg := range args {
+				targets = append(targets, target{src, argPath(loader, arg, "")})
+			}
+		case *kind != "":
+			src, err := shareSourceOf(cfg, *kind)
+			if err != nil {
+				return err
+			}
+			targets = append(targets, target{src, src.file})
+		default:
+			for _, src := range shareSources(cfg) {
+				targets = append(targets, target{src, src.file})
+			}
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("config.yaml 中没有启用的分享文件")
+		}
+
+		problems := 0
+		for _, t := range targets {
+			n, err := validateShareFile(loader, t.src, t.file)
+			if err != nil {
+				return fmt.Errorf("%s: %w", t.src.label, err)
+			}
+			problems += n
+		}
+
+		if problems > 0 {
+			return fmt.Errorf("发现 %d 个问题", problems)
+		}
+		log.Println("检查通过")
+		return nil
+	}
+	return c
+}
+
+// validateShareFile 检查一个分享文件, 把问题输出到标准输出, 返回问题数
+func validateShareFile(loader *config.Loader, src shareSource, file string) (int, error) {
+	entries, fileErrs, err := loader.LoadShareEntries(file)
+	if err != nil {
+		return 0, err
+	}
+
+	problems := fileErrs
+	validator, _ := src.provider.(provider.Validator)
+	for _, entry := range entries {
+		var err error
+		if validator != nil {
+			err = validator.Validate(entry.Value)
+		} else {
+			_, err = src.provider.BuildRequest("/"+entry.Label(), entry.Value)
+		}
+		for _, reason := range splitErrors(err) {
+			problems = append(problems, &config.FileError{
+				File: entry.File,
+				Line: entry.Line,
+				Msg:  fmt.Sprintf("%s: %v", entry.Label(), reason),
+			})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	for _, p := range problems {
+		p.File = displayPath(p.File)
+		fmt.Println(p)
+	}
+	log.Printf("%s: 共 %d 条, 问题 %d 个", src.label, len(entries), len(problems))
+	return len(problems), nil
+}
+
+// splitErrors 展开 errors.Join 合并的错误
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// displayPath 返回相对当前目录的路径, 便于在输出中点击定位
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+func newSecretCommand() *command {
+	c := newCommand("secret", `管理加密密钥文件 secrets.enc
+
+set NAME  从标准输入读取值写入密钥文件, 配置中以 secret:NAME 引用;
+口令从环境变量 OPENLIST_BATCH_PASSPHRASE 读取, 未设置时交互输入`, "set <名称>",
+		"openlist_batch secret set openlist_password",
+		"echo -n \"$TOKEN\" | OPENLIST_BATCH_PASSPHRASE=xxx openlist_batch secret set ali_token",
+	)
+
+	c.run = func(args []string) error {
+		if len(args) != 2 || args[0] != "set" {
+			c.usage()
+			return fmt.Errorf("用法: secret set <名称>")
+		}
+		name := args[1]
+
+		loader := newLoader()
+		if _, err := loader.LoadSecrets(); err != nil {
+			return fmt.Errorf("读取密钥文件失败: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "请输入 %s 的值: ", name)
+		value, err := readLine()
+		if err != nil {
+			return fmt.Errorf("读取输入失败: %w", err)
+		}
+		if err := loader.SetSecret(name, value); err != nil {
+			return fmt.Errorf("写入密钥文件失败: %w", err)
+		}
+		log.Printf("已写入 %s, 在配置中使用 secret:%s 引用", name, name)
+		return nil
+	}
+	return c
+}
+
+// sortedKeys 返回按字典序排序的键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
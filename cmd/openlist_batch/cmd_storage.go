@@ -0,0 +1,412 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+	"github.com/yzbtdiy/openlist_batch/internal/provider"
+	"github.com/yzbtdiy/openlist_batch/internal/service"
+)
+
+func newDeleteCommand() *command {
+	c := newCommand("delete", `批量删除存储
+
+-disabled、-all、-id 必须且只能指定一个; 删除前显示数量和部分挂载路径并要求确认,
+protected_paths 中的存储会跳过, 除非指定 -force`, "",
+		"openlist_batch delete -disabled",
+		"openlist_batch delete -id 3,5,8",
+		"openlist_batch delete -all",
+		"openlist_batch delete -disabled -yes",
+	)
+	disabled := c.flags.Bool("disabled", false, "删除已禁用的存储")
+	all := c.flags.Bool("all", false, "删除所有存储 (慎用)")
+	ids := c.flags.String("id", "", "删除指定 ID 的存储, 多个用逗号分隔")
+	yes := addYesFlag(c.flags)
+	force := addForceFlag(c.flags)
+
+	c.run = func(args []string) error {
+		modes := 0
+		for _, set := range []bool{*disabled, *all, *ids != ""} {
+			if set {
+				modes++
+			}
+		}
+		if modes != 1 {
+			c.usage()
+			return fmt.Errorf("-disabled、-all、-id 必须且只能指定一个")
+		}
+
+		svc, _, _, err := openService()
+		if err != nil {
+			return err
+		}
+		defer svc.Close()
+		svc.SetForce(*force)
+
+		list, err := svc.GetStorageList()
+		if err != nil {
+			return fmt.Errorf("获取存储列表失败: %w", err)
+		}
+
+		var targets []model.StorageItem
+		switch {
+		case *disabled:
+			for _, item := range list.Content {
+				if item.Disabled {
+					targets = append(targets, item)
+				}
+			}
+		case *all:
+			targets = list.Content
+		default:
+			targets = selectByID(list.Content, strings.Split(*ids, ","))
+		}
+
+		targets, protected := svc.SplitProtected(targets)
+		for _, item := range protected {
+			log.Printf("跳过受保护的存储 %d (%s)", item.Id, item.MountPath)
+		}
+		if len(targets) == 0 {
+			log.Println("没有需要删除的存储")
+			return nil
+		}
+
+		ok, err := confirm("将删除", targets, *yes)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			log.Println("已取消")
+			return nil
+		}
+
+		deleted, failed := svc.DeleteStorages(targets)
+		log.Printf("删除完成: 成功 %d, 失败 %d", deleted, failed)
+		return nil
+	}
+	return c
+}
+
+// selectByID 按 ID 选择存储, 无效或不存在的 ID 记录日志后忽略
+func selectByID(items []model.StorageItem, ids []string) []model.StorageItem {
+	var selected []model.StorageItem
+	for _, idStr := range ids {
+		id, err := strconv.Atoi(strings.TrimSpace(idStr))
+		if err != nil {
+			log.Printf("无效的存储 ID: %s", idStr)
+			continue
+		}
+		found := false
+		for _, item := range items {
+			if item.Id == id {
+				selected = append(selected, item)
+				found = true
+				break
+			}
+		}
+		if !found {
+			log.Printf("未找到存储 %d", id)
+		}
+	}
+	return selected
+}
+
+func newUpdateCommand() *command {
+	c := newCommand("update", `批量更新存储凭据
+
+用 config.yaml 中当前的凭据更新对应驱动的所有存储, 只替换凭据相关字段:
+  aliyunshare  refresh_token
+  aliyunopen   refresh_token、client_id、client_secret、use_online_api、alipan_type
+  pikpakshare  platform、device_id、use_transcoding_address
+  pikpakdrive  按 username 匹配账户的 refresh_token、password、platform、device_id
+  onedriveapp  按 client_id 与 tenant_id 匹配租户的 client_secret
+未指定类型时更新所有已启用的、支持更新凭据的类型 (不包括 protocol、local 和 alias).
+
+配置了 aliyun_share.token_source (aliyun_open.token_source) 或指定 -from 时,
+先从该存储读取 OpenList 轮换后的 refresh_token, 写回配置后再同步到同类型的其他存储;
+-from 只能用于 aliyunshare 和 aliyunopen 中的一个.
+
+凭据没有变化且状态正常的存储不会重复提交;
+protected_paths 中的存储会跳过, 除非指定 -force`, "[类型...]",
+		"openlist_batch update",
+		"openlist_batch update aliyunshare",
+		"openlist_batch update onedriveapp pikpakshare",
+		"openlist_batch update -from /阿里云盘/主账号 aliyunshare",
+		"openlist_batch update -from /我的阿里云盘/资料 aliyunopen",
+	)
+	force := addForceFlag(c.flags)
+	from := c.flags.String("from", "", "读取轮换后 refresh_token 的存储 (ID 或挂载路径), 默认使用对应类型的 token_source")
+
+	c.run = func(args []string) error {
+		svc, cfg, loader, err := openService()
+		if err != nil {
+			return err
+		}
+		defer svc.Close()
+		svc.SetForce(*force)
+
+		// 未指定类型时只更新支持更新凭据的类型
+		kinds := args
+		if len(kinds) == 0 {
+			for _, src := range shareSources(cfg) {
+				if _, ok := src.provider.(provider.UpdateableProvider); ok {
+					kinds = append(kinds, src.kind)
+				}
+			}
+		}
+		if len(kinds) == 0 {
+			return fmt.Errorf("config.yaml 中没有启用的支持更新凭据的类型")
+		}
+
+		var sources []shareSource
+		for _, kind := range kinds {
+			src, err := shareSourceOf(cfg, kind)
+			if err != nil {
+				return err
+			}
+			if !src.enable {
+				return fmt.Errorf("%s未启用", src.label)
+			}
+			sources = append(sources, src)
+		}
+
+		if *from != "" {
+			rotating := 0
+			for _, src := range sources {
+				if _, ok := tokenSources(cfg)[src.kind]; ok {
+					rotating++
+				}
+			}
+			if rotating != 1 {
+				return fmt.Errorf("-from 需要且只能与 aliyunshare、aliyunopen 中的一个类型一起使用")
+			}
+		}
+
+		var total service.RotateResult
+		for _, src := range sources {
+			if source, ok := tokenSources(cfg)[src.kind]; ok {
+				if *from != "" {
+					source = *from
+				}
+				if source != "" {
+					if err := rotateRefreshToken(svc, cfg, loader, src.kind, source); err != nil {
+						return err
+					}
+					// 用写回后的 refresh_token 重新创建提供商
+					src, _ = shareSourceOf(cfg, src.kind)
+				}
+			}
+
+			p, ok := src.provider.(provider.UpdateableProvider)
+			if !ok {
+				return fmt.Errorf("%s不支持更新凭据", src.label)
+			}
+			log.Printf("正在更新%s凭据...", src.label)
+			result, err := svc.RotateCredentials(p)
+			if err != nil {
+				return err
+			}
+			total.Merge(result)
+		}
+
+		log.Printf("更新完成: 更新 %d, 未变化 %d, 跳过 %d, 失败 %d", total.Updated, total.Unchanged, total.Skipped, total.Failed)
+		return nil
+	}
+	return c
+}
+
+func newTenantCommand() *command {
+	c := newCommand("tenant", `更新 OneDrive 租户下所有存储的应用凭据
+
+用 config.yaml 中该租户的 client_id 和 client_secret 更新所有 tenant_id 相同、
+client_id 匹配的 OnedriveAPP 存储, 完成后列出各存储状态; 租户可以是 id、序号、
+tenant_id 或 client_id. 应用重新注册 (client_id 变化) 时, 用 -from 指定旧的
+client_id 找到原有存储.
+
+有存储更新后状态不是 work 时以非零状态退出;
+protected_paths 中的存储会跳过, 除非指定 -force`, "<租户>",
+		"openlist_batch tenant 1",
+		"openlist_batch tenant -from 11111111-2222-3333-4444-555555555555 1",
+	)
+	force := addForceFlag(c.flags)
+	from := c.flags.String("from", "", "原有存储使用的旧 client_id, 默认为租户当前的 client_id")
+
+	c.run = func(args []string) error {
+		if len(args) != 1 {
+			c.usage()
+			return fmt.Errorf("需要指定一个租户")
+		}
+
+		svc, cfg, _, err := openService()
+		if err != nil {
+			return err
+		}
+		defer svc.Close()
+		svc.SetForce(*force)
+
+		tenant, err := cfg.OneDriveApp.FindTenant(args[0])
+		if err != nil {
+			return err
+		}
+
+		log.Printf("正在更新租户 %s 的存储...", tenant.TenantID)
+		result, mounts, err := svc.RotateTenantSecret(tenant, *from)
+		if err != nil {
+			return err
+		}
+		if len(mounts) == 0 {
+			return fmt.Errorf("没有找到租户 %s 的存储", tenant.TenantID)
+		}
+
+		broken := 0
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tPATH\tSTATUS\tUPDATED")
+		for _, m := range mounts {
+			if m.Status != "work" {
+				broken++
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%v\n", m.Id, m.MountPath, m.Status, m.Updated)
+		}
+		tw.Flush()
+
+		log.Printf("更新完成: 更新 %d, 未变化 %d, 跳过 %d, 失败 %d; 正常 %d/%d",
+			result.Updated, result.Unchanged, result.Skipped, result.Failed, len(mounts)-broken, len(mounts))
+		if broken > 0 {
+			return fmt.Errorf("%d 个存储状态异常", broken)
+		}
+		return nil
+	}
+	return c
+}
+
+// tokenSources 支持读取轮换后 refresh_token 的类型及其配置的 token_source
+func tokenSources(cfg *config.Config) map[string]string {
+	return map[string]string{
+		"aliyunshare": cfg.AliyunShare.TokenSource,
+		"aliyunopen":  cfg.AliyunOpen.TokenSource,
+	}
+}
+
+// rotateRefreshToken 读取 source 存储中轮换后的 refresh_token, 有变化时写回配置并更新 cfg
+func rotateRefreshToken(svc *service.BatchService, cfg *config.Config, loader *config.Loader, kind, source string) error {
+	current, save := &cfg.AliyunShare.RefreshToken, loader.SaveAliyunRefreshToken
+	if kind == "aliyunopen" {
+		current, save = &cfg.AliyunOpen.RefreshToken, loader.SaveAliyunOpenRefreshToken
+	}
+
+	token, item, err := svc.ReadRotatedRefreshToken(source)
+	if err != nil {
+		return fmt.Errorf("读取轮换后的 refresh_token 失败: %w", err)
+	}
+	if src, _ := shareSourceOf(cfg, kind); item.Driver != src.provider.Driver() {
+		return fmt.Errorf("存储 %d (%s) 的驱动为 %s, 不是 %s", item.Id, item.MountPath, item.Driver, src.provider.Driver())
+	}
+	if token == *current {
+		log.Printf("存储 %d (%s) 中的 refresh_token 与配置一致", item.Id, item.MountPath)
+		return nil
+	}
+
+	if err := save(cfg, token); err != nil {
+		return fmt.Errorf("写回 refresh_token 失败: %w", err)
+	}
+	*current = token
+	log.Printf("已从存储 %d (%s) 读取轮换后的 refresh_token 并写回配置", item.Id, item.MountPath)
+	return nil
+}
+
+func newExportCommand() *command {
+	c := newCommand("export", `导出存储到分享文件
+
+pikpakshare  导出 PikPakShare 存储, 可用 import 导入到其他实例`, "<类型>",
+		"openlist_batch export pikpakshare",
+		"openlist_batch export -o backup/pikpak.yaml pikpakshare",
+	)
+	output := c.flags.String("o", "", "导出文件路径, 默认为工作目录下的 <类型>_export.yaml")
+
+	c.run = func(args []string) error {
+		if len(args) != 1 {
+			c.usage()
+			return fmt.Errorf("需要指定一个类型")
+		}
+
+		svc, _, loader, err := openService()
+		if err != nil {
+			return err
+		}
+		defer svc.Close()
+
+		switch args[0] {
+		case "pikpakshare", "pikpak":
+			log.Println("正在导出 PikPakShare 存储...")
+			shareList, err := svc.ExportPikPakShare()
+			if err != nil {
+				return err
+			}
+			if len(shareList) == 0 {
+				log.Println("没有找到 PikPakShare 存储")
+				return nil
+			}
+			outputFile := argPath(loader, *output, "pikpak_share_export.yaml")
+			if err := loader.SaveShareList(outputFile, shareList); err != nil {
+				return fmt.Errorf("保存导出文件失败: %w", err)
+			}
+			log.Printf("已导出到 %s", outputFile)
+			return nil
+		default:
+			return fmt.Errorf("未知的导出类型: %s", args[0])
+		}
+	}
+	return c
+}
+
+func newCopyCommand() *command {
+	c := newCommand("copy", `从其他实例复制存储到当前实例
+
+挂载路径已存在时更新, 否则创建; 驱动过滤、路径改写按目标实例的 copy 配置执行,
+阿里云盘 refresh_token 等凭据替换为目标实例自己的配置; 目标实例 protected_paths
+中已存在的存储不会被覆盖, 除非指定 -force`, "",
+		"openlist_batch copy -from prod -profile mirror",
+	)
+	from := c.flags.String("from", "", "源实例名 (config.yaml 中 profiles 下的名称)")
+	force := addForceFlag(c.flags)
+
+	c.run = func(args []string) error {
+		if *from == "" {
+			c.usage()
+			return fmt.Errorf("需要指定 -from")
+		}
+
+		svc, _, loader, err := openService()
+		if err != nil {
+			return err
+		}
+		defer svc.Close()
+		svc.SetForce(*force)
+
+		srcCfg, err := loadProfile(loader, *from)
+		if err != nil {
+			return fmt.Errorf("源实例: %w", err)
+		}
+		src, err := connect(srcCfg, loader)
+		if err != nil {
+			return fmt.Errorf("连接源实例失败: %w", err)
+		}
+		defer src.Close()
+
+		log.Printf("正在从实例 %s 复制存储...", *from)
+		result, err := svc.CopyFrom(src)
+		if err != nil {
+			return err
+		}
+		log.Printf("复制完成: 创建 %d, 更新 %d, 跳过 %d, 失败 %d",
+			result.Created, result.Updated, result.Skipped, result.Failed)
+		return nil
+	}
+	return c
+}
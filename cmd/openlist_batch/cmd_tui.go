@@ -0,0 +1,727 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+	"github.com/yzbtdiy/openlist_batch/internal/service"
+	"github.com/yzbtdiy/openlist_batch/internal/terminal"
+)
+
+func newTUICommand() *command {
+	c := newCommand("tui", `交互式查看和批量修改存储
+
+按挂载路径以树形显示存储, 多选后可启用、禁用、重新加载、编辑字段或删除;
+提交前显示修改前后的对比, 确认后才调用 OpenList 接口. 只需要终端, 可在 SSH 中使用;
+protected_paths 中的存储不能删除或修改, 除非指定 -force
+
+按键:
+  ↑↓ j k 移动      ←→ h l 折叠/展开    PgUp PgDn 翻页
+  空格 选择/取消   a 全选/全不选       / 筛选 (语法同 list)
+  e 启用  d 禁用  r 重新加载  c 编辑字段  x 删除
+  R 重新获取列表   q 退出`, "",
+		"openlist_batch tui",
+		"openlist_batch tui -filter 'status!=work'",
+	)
+	filterExpr := c.flags.String("filter", "", "初始筛选表达式, 语法同 list")
+	force := addForceFlag(c.flags)
+
+	c.run = func(args []string) error {
+		filter, err := service.ParseFilter(*filterExpr)
+		if err != nil {
+			return err
+		}
+
+		svc, _, _, err := openService()
+		if err != nil {
+			return err
+		}
+		defer svc.Close()
+		svc.SetForce(*force)
+
+		ui := &tui{
+			svc:       svc,
+			filter:    filter,
+			filterStr: *filterExpr,
+			selected:  make(map[int]bool),
+			collapsed: make(map[string]bool),
+		}
+		if err := ui.reload(); err != nil {
+			return err
+		}
+
+		term, err := terminal.Open()
+		if err != nil {
+			return err
+		}
+		defer term.Close()
+		ui.term = term
+
+		return ui.run()
+	}
+	return c
+}
+
+// treeNode 按挂载路径组织的树节点, 挂载路径本身是存储时 item 不为空
+type treeNode struct {
+	name     string
+	path     string
+	item     *model.StorageItem
+	children []*treeNode
+}
+
+// treeRow 展开后的一行
+type treeRow struct {
+	node  *treeNode
+	depth int
+}
+
+// pendingChange 待提交的修改
+type pendingChange struct {
+	before model.StorageItem
+	after  model.StorageItem
+	delete bool
+}
+
+// tui 交互界面状态
+type tui struct {
+	term      *terminal.Terminal
+	svc       *service.BatchService
+	items     []model.StorageItem
+	filter    *service.Filter
+	filterStr string
+	root      *treeNode
+	rows      []treeRow
+	cursor    int
+	offset    int
+	selected  map[int]bool    // 按存储 ID
+	collapsed map[string]bool // 按路径, 重新获取列表后保持
+	message   string
+}
+
+// run 主循环
+func (t *tui) run() error {
+	for {
+		if err := t.term.Draw(t.render()); err != nil {
+			return err
+		}
+		ev, err := t.term.ReadEvent()
+		if err != nil {
+			return err
+		}
+
+		t.message = ""
+		switch {
+		case ev.Key == terminal.KeyCtrlC, ev.Rune == 'q':
+			return nil
+		case ev.Key == terminal.KeyUp, ev.Rune == 'k':
+			t.move(-1)
+		case ev.Key == terminal.KeyDown, ev.Rune == 'j':
+			t.move(1)
+		case ev.Key == terminal.KeyPageUp:
+			t.move(-t.pageSize())
+		case ev.Key == terminal.KeyPageDown:
+			t.move(t.pageSize())
+		case ev.Key == terminal.KeyHome, ev.Rune == 'g':
+			t.move(-len(t.rows))
+		case ev.Key == terminal.KeyEnd, ev.Rune == 'G':
+			t.move(len(t.rows))
+		case ev.Key == terminal.KeyRight, ev.Rune == 'l':
+			t.setCollapsed(false)
+		case ev.Key == terminal.KeyLeft, ev.Rune == 'h':
+			t.setCollapsed(true)
+		case ev.Key == terminal.KeyEnter:
+			if node := t.current(); node != nil && len(node.children) > 0 {
+				t.setCollapsed(!t.collapsed[node.path])
+			}
+		case ev.Rune == ' ':
+			t.toggleSelect()
+			t.move(1)
+		case ev.Rune == 'a':
+			t.toggleSelectAll()
+		case ev.Rune == '/':
+			t.editFilter()
+		case ev.Rune == 'R':
+			if err := t.reload(); err != nil {
+				t.message = err.Error()
+			}
+		case ev.Rune == 'e':
+			t.setDisabled(false)
+		case ev.Rune == 'd':
+			t.setDisabled(true)
+		case ev.Rune == 'r':
+			t.refreshStorages()
+		case ev.Rune == 'c':
+			t.editField()
+		case ev.Rune == 'x':
+			t.deleteStorages()
+		}
+	}
+}
+
+// reload 重新获取存储列表并重建树
+func (t *tui) reload() error {
+	list, err := t.svc.GetStorageList()
+	if err != nil {
+		return fmt.Errorf("获取存储列表失败: %w", err)
+	}
+	t.items = list.Content
+
+	// 已不存在的存储取消选择
+	exists := make(map[int]bool, len(t.items))
+	for _, item := range t.items {
+		exists[item.Id] = true
+	}
+	for id := range t.selected {
+		if !exists[id] {
+			delete(t.selected, id)
+		}
+	}
+
+	t.rebuild()
+	return nil
+}
+
+// rebuild 按筛选条件重建树, 光标尽量停留在原来的路径
+func (t *tui) rebuild() {
+	var cursorPath string
+	if node := t.current(); node != nil {
+		cursorPath = node.path
+	}
+
+	t.root = buildTree(t.filter.Apply(t.items))
+	t.flatten()
+
+	t.cursor = 0
+	for i, row := range t.rows {
+		if row.node.path == cursorPath {
+			t.cursor = i
+			break
+		}
+	}
+}
+
+// buildTree 按挂载路径构建树
+func buildTree(items []model.StorageItem) *treeNode {
+	root := &treeNode{}
+	for i := range items {
+		node := root
+		segments := splitSegments(items[i].MountPath)
+		if len(segments) == 0 {
+			segments = []string{""}
+		}
+
+		path := ""
+		for _, seg := range segments {
+			path += "/" + seg
+			var child *treeNode
+			for _, c := range node.children {
+				if c.name == seg {
+					child = c
+					break
+				}
+			}
+			if child == nil {
+				child = &treeNode{name: seg, path: path}
+				node.children = append(node.children, child)
+			}
+			node = child
+		}
+		node.item = &items[i]
+	}
+	sortTree(root)
+	return root
+}
+
+// splitSegments 切分挂载路径
+func splitSegments(mountPath string) []string {
+	var segments []string
+	for _, seg := range strings.Split(mountPath, "/") {
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	return segments
+}
+
+// sortTree 按名称排序子节点
+func sortTree(node *treeNode) {
+	sort.Slice(node.children, func(i, j int) bool {
+		return node.children[i].name < node.children[j].name
+	})
+	for _, c := range node.children {
+		sortTree(c)
+	}
+}
+
+// flatten 把展开的节点排成行
+func (t *tui) flatten() {
+	t.rows = t.rows[:0]
+	var visit func(node *treeNode, depth int)
+	visit = func(node *treeNode, depth int) {
+		for _, c := range node.children {
+			t.rows = append(t.rows, treeRow{node: c, depth: depth})
+			if !t.collapsed[c.path] {
+				visit(c, depth+1)
+			}
+		}
+	}
+	visit(t.root, 0)
+}
+
+// storagesUnder 返回节点及其子节点上的所有存储
+func storagesUnder(node *treeNode) []model.StorageItem {
+	var items []model.StorageItem
+	if node.item != nil {
+		items = append(items, *node.item)
+	}
+	for _, c := range node.children {
+		items = append(items, storagesUnder(c)...)
+	}
+	return items
+}
+
+// current 返回光标所在节点
+func (t *tui) current() *treeNode {
+	if t.cursor < 0 || t.cursor >= len(t.rows) {
+		return nil
+	}
+	return t.rows[t.cursor].node
+}
+
+// pageSize 列表区域的行数
+func (t *tui) pageSize() int {
+	_, height := t.term.Size()
+	return max(height-3, 1)
+}
+
+// move 移动光标
+func (t *tui) move(delta int) {
+	t.cursor = min(max(t.cursor+delta, 0), max(len(t.rows)-1, 0))
+}
+
+// setCollapsed 折叠或展开当前节点, 折叠叶子节点时移到父节点
+func (t *tui) setCollapsed(collapsed bool) {
+	node := t.current()
+	if node == nil {
+		return
+	}
+	if len(node.children) == 0 || (collapsed && t.collapsed[node.path]) {
+		if collapsed {
+			depth := t.rows[t.cursor].depth
+			for i := t.cursor - 1; i >= 0; i-- {
+				if t.rows[i].depth < depth {
+					t.cursor = i
+					break
+				}
+			}
+		}
+		return
+	}
+	t.collapsed[node.path] = collapsed
+	t.flatten()
+}
+
+// toggleSelect 选择或取消当前节点下的所有存储
+func (t *tui) toggleSelect() {
+	node := t.current()
+	if node == nil {
+		return
+	}
+	items := storagesUnder(node)
+	all := t.countSelected(items) == len(items)
+	for _, item := range items {
+		if all {
+			delete(t.selected, item.Id)
+		} else {
+			t.selected[item.Id] = true
+		}
+	}
+}
+
+// toggleSelectAll 选择或取消筛选结果中的所有存储
+func (t *tui) toggleSelectAll() {
+	items := storagesUnder(t.root)
+	if t.countSelected(items) == len(items) {
+		clear(t.selected)
+		return
+	}
+	for _, item := range items {
+		t.selected[item.Id] = true
+	}
+}
+
+// countSelected 统计已选择的存储数量
+func (t *tui) countSelected(items []model.StorageItem) int {
+	n := 0
+	for _, item := range items {
+		if t.selected[item.Id] {
+			n++
+		}
+	}
+	return n
+}
+
+// targets 返回操作对象: 有选择时为已选择的存储, 否则为当前节点下的存储
+func (t *tui) targets() []model.StorageItem {
+	if len(t.selected) > 0 {
+		var items []model.StorageItem
+		for _, item := range t.items {
+			if t.selected[item.Id] {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	if node := t.current(); node != nil {
+		return storagesUnder(node)
+	}
+	return nil
+}
+
+// editFilter 修改筛选条件
+func (t *tui) editFilter() {
+	expr, ok := t.prompt("筛选: ", t.filterStr)
+	if !ok {
+		return
+	}
+	filter, err := service.ParseFilter(expr)
+	if err != nil {
+		t.message = err.Error()
+		return
+	}
+	t.filter, t.filterStr = filter, expr
+	t.rebuild()
+}
+
+// setDisabled 启用或禁用存储
+func (t *tui) setDisabled(disabled bool) {
+	var changes []pendingChange
+	for _, item := range t.targets() {
+		if item.Disabled == disabled {
+			continue
+		}
+		after := item
+		after.Disabled = disabled
+		changes = append(changes, pendingChange{before: item, after: after})
+	}
+
+	title := "启用存储"
+	if disabled {
+		title = "禁用存储"
+	}
+	t.confirmAndApply(title, changes)
+}
+
+// refreshStorages 重新提交存储, 让 OpenList 重新加载
+func (t *tui) refreshStorages() {
+	var changes []pendingChange
+	for _, item := range t.targets() {
+		changes = append(changes, pendingChange{before: item, after: item})
+	}
+	t.confirmAndApply("重新加载存储", changes)
+}
+
+// deleteStorages 删除存储
+func (t *tui) deleteStorages() {
+	var changes []pendingChange
+	for _, item := range t.targets() {
+		changes = append(changes, pendingChange{before: item, delete: true})
+	}
+	t.confirmAndApply("删除存储", changes)
+}
+
+// editField 编辑存储字段
+func (t *tui) editField() {
+	items := t.targets()
+	if len(items) == 0 {
+		t.message = "没有选择存储"
+		return
+	}
+
+	input, ok := t.prompt("编辑 ("+strings.Join(service.EditableFields, ", ")+", addition.<键>) 字段=值: ", "")
+	if !ok || input == "" {
+		return
+	}
+	field, value, found := strings.Cut(input, "=")
+	if !found {
+		t.message = "格式应为 字段=值"
+		return
+	}
+	field, value = strings.TrimSpace(field), strings.TrimSpace(value)
+	if (field == "path" || field == "mount_path") && len(items) > 1 {
+		t.message = "挂载路径只能逐个修改"
+		return
+	}
+
+	var changes []pendingChange
+	for _, item := range items {
+		after := item
+		if err := service.SetStorageField(&after, field, value); err != nil {
+			t.message = err.Error()
+			return
+		}
+		if len(service.DiffStorage(item, after)) > 0 {
+			changes = append(changes, pendingChange{before: item, after: after})
+		}
+	}
+	t.confirmAndApply("编辑 "+field, changes)
+}
+
+// confirmAndApply 显示修改对比, 确认后提交
+func (t *tui) confirmAndApply(title string, changes []pendingChange) {
+	// 受保护的存储只允许重新加载
+	skipped := 0
+	allowed := changes[:0]
+	for _, c := range changes {
+		modifies := c.delete || len(service.DiffStorage(c.before, c.after)) > 0
+		if modifies && t.svc.IsProtected(c.before.MountPath) {
+			skipped++
+			continue
+		}
+		allowed = append(allowed, c)
+	}
+	changes = allowed
+
+	if len(changes) == 0 {
+		t.message = "没有需要修改的存储"
+		if skipped > 0 {
+			t.message = fmt.Sprintf("跳过 %d 个受保护的存储, 没有需要修改的存储", skipped)
+		}
+		return
+	}
+	if skipped > 0 {
+		title = fmt.Sprintf("%s (跳过 %d 个受保护的存储)", title, skipped)
+	}
+	if !t.confirm(title, changes) {
+		t.message = "已取消"
+		return
+	}
+
+	t.term.Draw([]string{terminal.Bold + title + ": 正在提交..."})
+	succeeded, failed := 0, 0
+	var firstErr error
+	for _, c := range changes {
+		var err error
+		if c.delete {
+			err = t.svc.DeleteStorage(c.before.Id)
+		} else {
+			err = t.svc.SaveStorage(c.after)
+		}
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", c.before.MountPath, err)
+			}
+			continue
+		}
+		succeeded++
+		if c.delete {
+			delete(t.selected, c.before.Id)
+		}
+	}
+
+	t.message = fmt.Sprintf("%s: 成功 %d, 失败 %d", title, succeeded, failed)
+	if firstErr != nil {
+		t.message += ", " + firstErr.Error()
+	}
+	if err := t.reload(); err != nil {
+		t.message += ", " + err.Error()
+	}
+}
+
+// confirm 显示修改对比, 按 y 确认
+func (t *tui) confirm(title string, changes []pendingChange) bool {
+	var body []string
+	for _, c := range changes {
+		header := fmt.Sprintf("#%d %s %s", c.before.Id, c.before.MountPath, terminal.Gray+c.before.Driver)
+		switch diff := service.DiffStorage(c.before, c.after); {
+		case c.delete:
+			body = append(body, terminal.Red+"- "+header+terminal.Red+" 删除")
+		case len(diff) == 0:
+			body = append(body, terminal.Cyan+"~ "+header+terminal.Cyan+" 重新加载")
+		default:
+			body = append(body, terminal.Yellow+"~ "+header)
+			for _, d := range diff {
+				body = append(body,
+					terminal.Red+"    - "+d.Field+": "+d.Before,
+					terminal.Green+"    + "+d.Field+": "+d.After)
+			}
+		}
+	}
+
+	offset := 0
+	for {
+		_, height := t.term.Size()
+		view := max(height-2, 1)
+		offset = min(max(offset, 0), max(len(body)-view, 0))
+
+		lines := []string{fmt.Sprintf("%s%s: %d 个存储", terminal.Bold, title, len(changes))}
+		lines = append(lines, body[offset:min(offset+view, len(body))]...)
+		for len(lines) < height-1 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, terminal.Gray+"y 确认提交  ↑↓ PgUp PgDn 滚动  其他键取消")
+		if err := t.term.Draw(lines); err != nil {
+			return false
+		}
+
+		ev, err := t.term.ReadEvent()
+		if err != nil {
+			return false
+		}
+		switch {
+		case ev.Rune == 'y' || ev.Rune == 'Y':
+			return true
+		case ev.Key == terminal.KeyUp, ev.Rune == 'k':
+			offset--
+		case ev.Key == terminal.KeyDown, ev.Rune == 'j':
+			offset++
+		case ev.Key == terminal.KeyPageUp:
+			offset -= view
+		case ev.Key == terminal.KeyPageDown:
+			offset += view
+		default:
+			return false
+		}
+	}
+}
+
+// prompt 在底部读取一行输入, Esc 取消
+func (t *tui) prompt(label, initial string) (string, bool) {
+	input := []rune(initial)
+	for {
+		lines := t.render()
+		lines[len(lines)-1] = terminal.Cyan + label + terminal.Reset + string(input) + terminal.Reverse + " "
+		if err := t.term.Draw(lines); err != nil {
+			return "", false
+		}
+
+		ev, err := t.term.ReadEvent()
+		if err != nil {
+			return "", false
+		}
+		switch ev.Key {
+		case terminal.KeyEnter:
+			return strings.TrimSpace(string(input)), true
+		case terminal.KeyEsc, terminal.KeyCtrlC:
+			return "", false
+		case terminal.KeyBackspace:
+			if len(input) > 0 {
+				input = input[:len(input)-1]
+			}
+		case terminal.KeyRune:
+			input = append(input, ev.Rune)
+		}
+	}
+}
+
+// render 生成整屏内容
+func (t *tui) render() []string {
+	width, height := t.term.Size()
+	view := max(height-3, 1)
+
+	// 保持光标可见
+	if t.cursor < t.offset {
+		t.offset = t.cursor
+	}
+	if t.cursor >= t.offset+view {
+		t.offset = t.cursor - view + 1
+	}
+	t.offset = min(t.offset, max(len(t.rows)-view, 0))
+
+	visible := storagesUnder(t.root)
+	header := fmt.Sprintf("%sOpenList 存储  显示 %d / %d  已选 %d", terminal.Bold, len(visible), len(t.items), len(t.selected))
+	if t.filterStr != "" {
+		header += "  筛选: " + t.filterStr
+	}
+	lines := []string{header}
+
+	for i := t.offset; i < min(t.offset+view, len(t.rows)); i++ {
+		lines = append(lines, t.renderRow(t.rows[i], i == t.cursor, width))
+	}
+	if len(t.rows) == 0 {
+		lines = append(lines, terminal.Gray+"  (没有存储)")
+	}
+	for len(lines) < height-2 {
+		lines = append(lines, "")
+	}
+
+	lines = append(lines,
+		terminal.Yellow+t.message,
+		terminal.Gray+"空格 选择  a 全选  / 筛选  e 启用  d 禁用  r 重新加载  c 编辑  x 删除  R 刷新列表  q 退出")
+	return lines
+}
+
+// renderRow 生成一行树节点
+func (t *tui) renderRow(row treeRow, cursor bool, width int) string {
+	node := row.node
+	var b strings.Builder
+
+	if cursor {
+		b.WriteString(terminal.Bold + "> " + terminal.Reset)
+	} else {
+		b.WriteString("  ")
+	}
+	b.WriteString(strings.Repeat("  ", row.depth))
+
+	switch {
+	case len(node.children) == 0:
+		b.WriteString("  ")
+	case t.collapsed[node.path]:
+		b.WriteString("▸ ")
+	default:
+		b.WriteString("▾ ")
+	}
+
+	items := storagesUnder(node)
+	switch n := t.countSelected(items); {
+	case n == 0:
+		b.WriteString("[ ] ")
+	case n == len(items):
+		b.WriteString("[x] ")
+	default:
+		b.WriteString("[-] ")
+	}
+
+	name := node.name
+	if name == "" {
+		name = "/"
+	}
+	if node.item == nil {
+		name = terminal.Cyan + name + "/" + terminal.Reset
+	}
+	if cursor {
+		name = terminal.Reverse + name + terminal.Reset
+	}
+	b.WriteString(name)
+
+	if item := node.item; item != nil {
+		// 右侧信息尽量对齐
+		left := terminal.Width(b.String())
+		col := max(width*3/5, left+2)
+		b.WriteString(strings.Repeat(" ", col-left))
+		fmt.Fprintf(&b, "%s#%-4d %-18s ", terminal.Gray, item.Id, item.Driver)
+		b.WriteString(statusText(*item))
+		if t.svc.IsProtected(item.MountPath) {
+			b.WriteString(terminal.Yellow + " 受保护")
+		}
+	} else if len(node.children) > 0 && t.collapsed[node.path] {
+		fmt.Fprintf(&b, " %s(%d)", terminal.Gray, len(items))
+	}
+	return b.String()
+}
+
+// statusText 带颜色的存储状态
+func statusText(item model.StorageItem) string {
+	switch {
+	case item.Disabled:
+		return terminal.Gray + "已禁用"
+	case item.Status == "work":
+		return terminal.Green + item.Status
+	default:
+		return terminal.Red + firstLine(item.Status)
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+	"github.com/yzbtdiy/openlist_batch/internal/provider"
+	"github.com/yzbtdiy/openlist_batch/internal/service"
+)
+
+// options 通用参数
+var options struct {
+	config  string
+	workDir string
+	profile string
+}
+
+// stdin 共享的标准输入读取器, 避免多次创建时丢失缓冲内容
+var stdin = bufio.NewReader(os.Stdin)
+
+// addCommonFlags 注册通用参数, 命令前后均可使用
+func addCommonFlags(fs *flag.FlagSet) {
+	fs.StringVar(&options.config, "config", options.config, "配置文件路径, 默认依次查找 ./config.yaml 和 $XDG_CONFIG_HOME/openlist_batch/config.yaml")
+	fs.StringVar(&options.workDir, "workdir", options.workDir, "工作目录, 分享文件、密钥与 token 缓存的相对路径基于此目录, 默认为配置文件所在目录")
+	fs.StringVar(&options.profile, "profile", options.profile, "使用 config.yaml 中 profiles 下的命名实例, 默认使用顶层配置")
+}
+
+// addForceFlag 注册 -force 参数
+func addForceFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("force", false, "允许删除或覆盖 protected_paths 中受保护的存储")
+}
+
+// policyFlags 添加类命令的 -duplicate 和 -verify 参数
+type policyFlags struct {
+	duplicate *string
+	verify    *string
+}
+
+// addPolicyFlags 注册 -duplicate 和 -verify 参数
+func addPolicyFlags(fs *flag.FlagSet) policyFlags {
+	return policyFlags{
+		duplicate: fs.String("duplicate", "", "重复分享的处理方式: skip 跳过, report 记录后仍然添加, replace 删除已有的存储后添加; 默认使用 config.yaml 中的 duplicate"),
+		verify:    fs.String("verify", "", "新建存储后列出根目录验证: off 不验证, report 报告, disable 报告并禁用, delete 报告并删除; 默认使用 config.yaml 中的 verify"),
+	}
+}
+
+// apply 用命令行参数覆盖配置中的重复分享和验证处理方式
+func (f policyFlags) apply(cfg *config.Config) error {
+	if *f.duplicate == "" && *f.verify == "" {
+		return nil
+	}
+	if *f.duplicate != "" {
+		cfg.Duplicate = *f.duplicate
+	}
+	if *f.verify != "" {
+		cfg.Verify = *f.verify
+	}
+	return cfg.Validate()
+}
+
+// logResult 输出批量添加的结果统计和验证结果, prefix 为日志开头的说明
+func logResult(prefix string, r service.Result) {
+	log.Printf("%s: 成功 %d, 替换 %d, 跳过 %d, 失败 %d", prefix, r.Added, r.Replaced, r.Skipped, r.Failed)
+	logVerify(r.Verify)
+}
+
+// splitPatterns 拆分 -include / -exclude 中逗号分隔的通配符, 参数为空时返回 nil
+func splitPatterns(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// logVerify 输出新建存储的验证结果, 没有验证时不输出
+func logVerify(v service.VerifyResult) {
+	if v == (service.VerifyResult{}) {
+		return
+	}
+	log.Printf("验证结果: 正常 %d, 为空 %d, 失败 %d, 已禁用 %d, 已删除 %d", v.OK, v.Empty, v.Broken, v.Disabled, v.Deleted)
+}
+
+// addYesFlag 注册 -yes 参数
+func addYesFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("yes", false, "不询问, 直接确认 (用于脚本)")
+}
+
+// newLoader 按通用参数创建配置加载器
+func newLoader() *config.Loader {
+	workDir, configFile := config.ResolveLocation(options.config, options.workDir)
+	loader := config.NewLoader(workDir)
+	loader.SetConfigFile(configFile)
+	loader.SetPassphraseFunc(promptPassphrase)
+	return loader
+}
+
+// loadConfig 加载并验证当前实例的配置
+func loadConfig(loader *config.Loader) (*config.Config, error) {
+	return loadProfile(loader, options.profile)
+}
+
+// loadProfile 加载并验证指定实例的配置
+func loadProfile(loader *config.Loader, name string) (*config.Config, error) {
+	if !loader.FileExists(loader.ConfigPath()) {
+		return nil, fmt.Errorf("配置文件 %s 不存在, 请先运行 openlist_batch init", loader.ConfigPath())
+	}
+
+	cfg, err := loader.LoadProfile(name)
+	if err != nil {
+		return nil, fmt.Errorf("加载配置失败: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("配置验证失败: %w", err)
+	}
+	return cfg, nil
+}
+
+// connect 创建批处理服务, token 无效时先改用 config.yaml 中的 token, 仍无效时自动刷新
+func connect(cfg *config.Config, loader *config.Loader) (*service.BatchService, error) {
+	svc := service.NewBatchService(cfg, loader)
+
+	if !svc.ValidateToken() && !svc.UseConfigToken() {
+		log.Println("Token 无效，正在刷新...")
+		if err := svc.RefreshToken(); err != nil {
+			svc.Close()
+			return nil, fmt.Errorf("刷新 Token 失败: %w", err)
+		}
+	}
+
+	return svc, nil
+}
+
+// openService 加载当前实例配置并连接
+func openService() (*service.BatchService, *config.Config, *config.Loader, error) {
+	loader := newLoader()
+	cfg, err := loadConfig(loader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	svc, err := connect(cfg, loader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return svc, cfg, loader, nil
+}
+
+// addShares 用 src 的提供商添加 shares 并输出结果, action 为日志中的动作 (添加、导入)
+func addShares(svc *service.BatchService, src shareSource, shares config.ShareList, action string) error {
+	log.Printf("正在%s%s...", action, src.label)
+	result, err := svc.BatchAddShares(src.provider, shares)
+	if err != nil {
+		return err
+	}
+	logResult(action+"完成", result)
+	return nil
+}
+
+// connectAndAdd 连接实例后添加 shares, 用于先生成挂载列表再按 -add 添加的命令
+func connectAndAdd(cfg *config.Config, loader *config.Loader, src shareSource, shares config.ShareList) error {
+	svc, err := connect(cfg, loader)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+	return addShares(svc, src, shares, "添加")
+}
+
+// shareSource 已启用的分享文件及其提供商
+type shareSource struct {
+	kind     string // 命令行中的类型名
+	label    string
+	enable   bool
+	template string
+	file     string
+	provider provider.Provider
+}
+
+// shareSources 返回配置中已启用的分享来源
+func shareSources(cfg *config.Config) []shareSource {
+	var sources []shareSource
+	for _, kind := range shareKinds {
+		src, _ := shareSourceOf(cfg, kind)
+		if src.enable {
+			sources = append(sources, src)
+		}
+	}
+	return sources
+}
+
+// shareKinds 支持的分享类型, 别名引用其他存储, 放在最后
+var shareKinds = []string{"aliyunshare", "aliyunopen", "pikpakshare", "pikpakdrive", "onedriveapp", "protocol", "local", "alias"}
+
+// shareSourceOf 按类型名返回分享来源, 不检查是否启用
+func shareSourceOf(cfg *config.Config, kind string) (shareSource, error) {
+	switch strings.ToLower(kind) {
+	case "aliyunshare", "ali", "aliyun":
+		return shareSource{
+			kind:     "aliyunshare",
+			label:    "阿里云盘分享",
+			enable:   cfg.AliyunShare.Enable,
+			template: config.AliyunShareFile,
+			file:     cfg.AliyunShare.ShareFile(),
+			provider: provider.NewAliyunShare(cfg.AliyunShare.RefreshToken),
+		}, nil
+	case "aliyunopen":
+		return shareSource{
+			kind:     "aliyunopen",
+			label:    "阿里云盘 Open",
+			enable:   cfg.AliyunOpen.Enable,
+			template: config.AliyunOpenFile,
+			file:     cfg.AliyunOpen.ShareFile(),
+			provider: provider.NewAliyunOpen(cfg.AliyunOpen),
+		}, nil
+	case "pikpakshare", "pikpak":
+		return shareSource{
+			kind:     "pikpakshare",
+			label:    "PikPak 分享",
+			enable:   cfg.PikPakShare.Enable,
+			template: config.PikPakShareFile,
+			file:     cfg.PikPakShare.ShareFile(),
+			provider: provider.NewPikPakShare(cfg.PikPakShare.Platform, cfg.PikPakShare.DeviceID, cfg.PikPakShare.UseTranscodingAddress),
+		}, nil
+	case "pikpakdrive":
+		return shareSource{
+			kind:     "pikpakdrive",
+			label:    "PikPak 网盘",
+			enable:   cfg.PikPak.Enable,
+			template: config.PikPakFile,
+			file:     cfg.PikPak.ShareFile(),
+			provider: provider.NewPikPak(cfg.PikPak.AccountList()),
+		}, nil
+	case "onedriveapp", "onedrive":
+		return shareSource{
+			kind:     "onedriveapp",
+			label:    "OneDrive 应用",
+			enable:   cfg.OneDriveApp.Enable,
+			template: config.OneDriveAppFile,
+			file:     cfg.OneDriveApp.ShareFile(),
+			provider: provider.NewOneDriveApp(cfg.OneDriveApp),
+		}, nil
+	case "protocol", "protocols":
+		return shareSource{
+			kind:     "protocol",
+			label:    "协议存储",
+			enable:   cfg.Protocols.Enable,
+			template: config.ProtocolsFile,
+			file:     cfg.Protocols.ShareFile(),
+			provider: provider.NewProtocol(cfg.ResolveSecret),
+		}, nil
+	case "local":
+		return shareSource{
+			kind:     "local",
+			label:    "本地存储",
+			enable:   cfg.Local.Enable,
+			template: config.LocalFile,
+			file:     cfg.Local.ShareFile(),
+			provider: provider.NewLocal(),
+		}, nil
+	case "alias":
+		return shareSource{
+			kind:     "alias",
+			label:    "别名存储",
+			enable:   cfg.Alias.Enable,
+			template: config.AliasFile,
+			file:     cfg.Alias.ShareFile(),
+			provider: provider.NewAlias(),
+		}, nil
+	}
+	return shareSource{}, fmt.Errorf("未知的类型: %s, 可选值: %s", kind, strings.Join(shareKinds, ", "))
+}
+
+// ensureShareFiles 检查已启用提供商的分享文件, 不存在时生成模板并返回 false
+func ensureShareFiles(loader *config.Loader, cfg *config.Config) (bool, error) {
+	for _, src := range shareSources(cfg) {
+		if loader.FileExists(src.file) {
+			continue
+		}
+		log.Printf("%s文件 %s 不存在，正在生成...", src.label, src.file)
+		if err := loader.GenerateTemplateAs(src.template, src.file); err != nil {
+			return false, fmt.Errorf("生成%s文件失败: %w", src.label, err)
+		}
+		log.Printf("已生成 %s，请配置后重新运行", loader.Path(src.file))
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// argPath 返回命令行指定的文件路径, 按当前目录解析, 未指定时使用工作目录下的默认文件
+func argPath(loader *config.Loader, path, def string) string {
+	if path == "" {
+		return loader.Path(def)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+// promptPassphrase 从标准输入读取密钥文件口令
+func promptPassphrase() (string, error) {
+	fmt.Fprintf(os.Stderr, "请输入密钥文件口令 (也可设置环境变量 %s): ", config.PassphraseEnv)
+	return readLine()
+}
+
+// confirmSample 确认时最多显示的挂载路径数量
+const confirmSample = 10
+
+// confirm 显示受影响的存储数量和部分挂载路径, 请求用户确认; assumeYes 时直接确认
+func confirm(action string, items []model.StorageItem, assumeYes bool) (bool, error) {
+	if assumeYes {
+		return true, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "%s %d 个存储:\n", action, len(items))
+	for i, item := range items {
+		if i == confirmSample {
+			fmt.Fprintf(os.Stderr, "  ... 以及其他 %d 个\n", len(items)-i)
+			break
+		}
+		fmt.Fprintf(os.Stderr, "  %d %s\n", item.Id, item.MountPath)
+	}
+	fmt.Fprint(os.Stderr, "确认继续? [y/N]: ")
+
+	answer, err := readLine()
+	if err != nil {
+		return false, fmt.Errorf("读取确认失败, 非交互环境请使用 -yes: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// readLine 从标准输入读取一行
+func readLine() (string, error) {
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -4,210 +4,145 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"os"
-
-	"github.com/yzbtdiy/openlist_batch/internal/config"
-	"github.com/yzbtdiy/openlist_batch/internal/provider"
-	"github.com/yzbtdiy/openlist_batch/internal/service"
+	"strings"
 )
 
-var (
-	deleteFlag = flag.String("delete", "", `删除存储:
-  dis    删除已禁用存储
-  all    删除所有存储(慎用)`)
-
-	updateFlag = flag.String("update", "", `更新存储:
-  ali    更新阿里云盘 refresh_token`)
-
-	exportFlag = flag.String("export", "", `导出存储到yaml文件:
-  pikpakshare    导出 PikPakShare 存储`)
-)
+// command 子命令
+type command struct {
+	name     string
+	summary  string
+	args     string // 位置参数说明, 用于用法行
+	examples []string
+	flags    *flag.FlagSet
+	run      func(args []string) error
+}
 
-func main() {
-	flag.Parse()
+// commands 返回所有子命令, 顺序即帮助中的显示顺序
+func commands() []*command {
+	return []*command{
+		newInitCommand(),
+		newCheckCommand(),
+		newValidateCommand(),
+		newAddCommand(),
+		newSyncCommand(),
+		newImportCommand(),
+		newUsersCommand(),
+		newScanCommand(),
+		newAggregateCommand(),
+		newListCommand(),
+		newInspectCommand(),
+		newTUICommand(),
+		newDeleteCommand(),
+		newUpdateCommand(),
+		newTenantCommand(),
+		newExportCommand(),
+		newCopyCommand(),
+		newSecretCommand(),
+	}
+}
 
-	loader := config.NewLoader(".")
+// newCommand 创建子命令并注册通用参数
+func newCommand(name, summary, args string, examples ...string) *command {
+	c := &command{
+		name:     name,
+		summary:  summary,
+		args:     args,
+		examples: examples,
+		flags:    flag.NewFlagSet(name, flag.ContinueOnError),
+	}
+	addCommonFlags(c.flags)
+	c.flags.Usage = c.usage
+	return c
+}
 
-	// 检查并生成配置文件
-	if !loader.FileExists(config.ConfigFile) {
-		log.Println("配置文件不存在，正在生成...")
-		if err := loader.GenerateTemplate(config.ConfigFile); err != nil {
-			log.Fatalf("生成配置文件失败: %v", err)
+// usage 打印子命令帮助
+func (c *command) usage() {
+	out := c.flags.Output()
+	fmt.Fprintf(out, "用法: openlist_batch %s [参数] %s\n\n%s\n\n参数:\n", c.name, c.args, c.summary)
+	c.flags.PrintDefaults()
+	if len(c.examples) > 0 {
+		fmt.Fprintln(out, "\n示例:")
+		for _, e := range c.examples {
+			fmt.Fprintf(out, "  %s\n", e)
 		}
-		log.Println("已生成 config.yaml，请配置后重新运行")
-		return
 	}
+}
 
-	// 加载配置
-	cfg, err := loader.LoadConfig()
-	if err != nil {
-		log.Fatalf("加载配置失败: %v", err)
-	}
+func main() {
+	flag.Usage = usage
+	addCommonFlags(flag.CommandLine)
+	flag.Parse()
 
-	// 验证配置
-	if err := cfg.Validate(); err != nil {
-		log.Fatalf("配置验证失败: %v", err)
+	// 不带命令时只显示帮助, 不执行任何操作
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
 	}
 
-	// 检查分享文件
-	if cfg.AliyunShare.Enable && !loader.FileExists(config.AliyunShareFile) {
-		log.Println("阿里云盘分享文件不存在，正在生成...")
-		if err := loader.GenerateTemplate(config.AliyunShareFile); err != nil {
-			log.Fatalf("生成分享文件失败: %v", err)
-		}
-		log.Println("已生成 aliyun_share.yaml，请添加分享链接后重新运行")
+	name, args := flag.Arg(0), flag.Args()[1:]
+	if name == "help" {
+		runHelp(args)
 		return
 	}
 
-	if cfg.PikPakShare.Enable && !loader.FileExists(config.PikPakShareFile) {
-		log.Println("PikPak 分享文件不存在，正在生成...")
-		if err := loader.GenerateTemplate(config.PikPakShareFile); err != nil {
-			log.Fatalf("生成分享文件失败: %v", err)
+	for _, c := range commands() {
+		if c.name != name {
+			continue
 		}
-		log.Println("已生成 pikpak_share.yaml，请添加分享链接后重新运行")
-		return
-	}
-
-	if cfg.OneDriveApp.Enable && !loader.FileExists(config.OneDriveAppFile) {
-		log.Println("OneDrive 配置文件不存在，正在生成...")
-		if err := loader.GenerateTemplate(config.OneDriveAppFile); err != nil {
-			log.Fatalf("生成配置文件失败: %v", err)
+		if err := c.flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			os.Exit(2)
 		}
-		log.Println("已生成 onedrive_app.yaml，请配置后重新运行")
-		return
-	}
-
-	// 创建批处理服务
-	svc := service.NewBatchService(cfg, loader)
-	defer svc.Close()
-
-	// 验证或刷新 Token
-	if !svc.ValidateToken() {
-		log.Println("Token 无效，正在刷新...")
-		if err := svc.RefreshToken(); err != nil {
-			log.Fatalf("刷新 Token 失败: %v", err)
+		if err := c.run(c.flags.Args()); err != nil {
+			log.Fatalf("%s 失败: %v", c.name, err)
 		}
-		log.Println("请重新运行程序")
-		return
-	}
-
-	// 处理删除命令
-	if *deleteFlag != "" {
-		handleDelete(svc, *deleteFlag)
 		return
 	}
 
-	// 处理更新命令
-	if *updateFlag != "" {
-		handleUpdate(svc, cfg, *updateFlag)
-		return
-	}
-
-	// 处理导出命令
-	if *exportFlag != "" {
-		handleExport(svc, loader, *exportFlag)
-		return
-	}
-
-	// 批量添加存储
-	addStorages(svc, cfg, loader)
+	fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", name)
+	usage()
+	os.Exit(2)
 }
 
-func handleDelete(svc *service.BatchService, mode string) {
-	switch mode {
-	case "dis":
-		log.Println("正在删除禁用的存储...")
-		if err := svc.DeleteDisabledStorages(); err != nil {
-			log.Fatalf("删除失败: %v", err)
-		}
-	case "all":
-		log.Println("警告: 正在删除所有存储!")
-		if err := svc.DeleteAllStorages(); err != nil {
-			log.Fatalf("删除失败: %v", err)
-		}
-	default:
-		log.Printf("未知的删除模式: %s", mode)
-		os.Exit(1)
-	}
-}
-
-func handleUpdate(svc *service.BatchService, cfg *config.Config, mode string) {
-	switch mode {
-	case "ali":
-		if !cfg.AliyunShare.Enable {
-			log.Fatal("阿里云盘未启用")
-		}
-		log.Println("正在更新阿里云盘 RefreshToken...")
-		if err := svc.UpdateAliyunRefreshToken(cfg.AliyunShare.RefreshToken); err != nil {
-			log.Fatalf("更新失败: %v", err)
-		}
-	default:
-		log.Printf("未知的更新模式: %s", mode)
-		os.Exit(1)
+// runHelp 打印总帮助或指定子命令的帮助
+func runHelp(args []string) {
+	if len(args) == 0 {
+		flag.CommandLine.SetOutput(os.Stdout)
+		usage()
+		return
 	}
-}
-
-func handleExport(svc *service.BatchService, loader *config.Loader, mode string) {
-	switch mode {
-	case "pikpakshare":
-		log.Println("正在导出 PikPakShare 存储...")
-		shareList, err := svc.ExportPikPakShare()
-		if err != nil {
-			log.Fatalf("导出失败: %v", err)
-		}
-		if len(shareList) == 0 {
-			log.Println("没有找到 PikPakShare 存储")
+	for _, c := range commands() {
+		if c.name == args[0] {
+			c.flags.SetOutput(os.Stdout)
+			c.usage()
 			return
 		}
-		outputFile := "pikpak_share_export.yaml"
-		if err := loader.SaveShareList(outputFile, shareList); err != nil {
-			log.Fatalf("保存导出文件失败: %v", err)
-		}
-		log.Printf("已导出到 %s", outputFile)
-	default:
-		log.Printf("未知的导出模式: %s", mode)
-		os.Exit(1)
 	}
+	fmt.Fprintf(os.Stderr, "未知命令: %s\n", args[0])
+	os.Exit(2)
 }
 
-func addStorages(svc *service.BatchService, cfg *config.Config, loader *config.Loader) {
-	// 添加阿里云盘分享
-	if cfg.AliyunShare.Enable {
-		shares, err := loader.LoadShareList(config.AliyunShareFile)
-		if err != nil {
-			log.Printf("加载阿里云盘分享失败: %v", err)
-		} else {
-			log.Println("正在添加阿里云盘分享...")
-			aliyun := provider.NewAliyunShare(cfg.AliyunShare.RefreshToken)
-			svc.BatchAddShares(aliyun, shares)
-		}
-	}
-
-	// 添加 PikPak 分享
-	if cfg.PikPakShare.Enable {
-		shares, err := loader.LoadShareList(config.PikPakShareFile)
-		if err != nil {
-			log.Printf("加载 PikPak 分享失败: %v", err)
-		} else {
-			log.Println("正在添加 PikPak 分享...")
-			pikpak := provider.NewPikPakShare(cfg.PikPakShare.Platform, cfg.PikPakShare.UseTranscodingAddress)
-			svc.BatchAddShares(pikpak, shares)
-		}
-	}
-
-	// 添加 OneDrive 应用
-	if cfg.OneDriveApp.Enable {
-		shares, err := loader.LoadShareList(config.OneDriveAppFile)
-		if err != nil {
-			log.Printf("加载 OneDrive 配置失败: %v", err)
-		} else {
-			log.Println("正在添加 OneDrive 应用...")
-			onedrive := provider.NewOneDriveApp(cfg.OneDriveApp.Region, cfg.OneDriveApp.Tenants)
-			svc.BatchAddOneDriveApp(onedrive, shares)
-		}
+// usage 打印总帮助
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprint(out, "OpenList 批量存储管理工具\n\n用法: openlist_batch [通用参数] <命令> [参数]\n\n命令:\n")
+	for _, c := range commands() {
+		fmt.Fprintf(out, "  %-8s %s\n", c.name, firstLine(c.summary))
 	}
+	fmt.Fprint(out, "\n通用参数 (也可写在命令之后):\n")
+	flag.PrintDefaults()
+	fmt.Fprint(out, "\n使用 \"openlist_batch help <命令>\" 查看命令的详细参数和示例\n")
+}
 
-	log.Println("批量操作完成")
+// firstLine 返回文本的第一行
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
 }
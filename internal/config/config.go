@@ -1,41 +1,213 @@
 // Package config 处理应用程序配置
 package config
 
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
 // Config 主配置结构
+//
+// 顶层字段为默认实例, profiles 中的命名实例在其基础上覆盖
 type Config struct {
 	URL         string      `yaml:"url"`
 	Auth        Auth        `yaml:"auth"`
 	Token       string      `yaml:"token"`
+	TokenFile   string      `yaml:"token_file"`
+	TokenStore  string      `yaml:"token_store"`
 	AliyunShare AliyunShare `yaml:"aliyun_share"`
+	AliyunOpen  AliyunOpen  `yaml:"aliyun_open"`
 	PikPakShare PikPakShare `yaml:"pikpak_share"`
+	PikPak      PikPak      `yaml:"pikpak"`
 	OneDriveApp OneDriveApp `yaml:"onedrive_app"`
+	Protocols   Protocols   `yaml:"protocols"`
+	Local       Local       `yaml:"local"`
+	Alias       Alias       `yaml:"alias"`
+	Copy        Copy        `yaml:"copy"`
+	MountPath   MountPath   `yaml:"mount_path"`
+	Duplicate   string      `yaml:"duplicate"` // 重复分享的处理方式, 默认 skip
+	Verify      string      `yaml:"verify"`    // 新建存储后的验证方式, 默认 off
+
+	// ProtectedPaths 受保护的挂载路径, 批量命令不会删除或覆盖, 除非指定 -force
+	ProtectedPaths []string `yaml:"protected_paths"`
+
+	Profiles map[string]yaml.Node `yaml:"profiles"`
+
+	// Profile 当前使用的实例名, 为空表示顶层默认实例
+	Profile string `yaml:"-"`
+
+	// loader 加载该配置的 Loader, 用于解析配置以外的敏感字段
+	loader *Loader
+
+	// configToken 被 token 缓存覆盖的 config.yaml 中的 token
+	configToken string
 }
 
+// ConfigToken 返回被 token 缓存覆盖的 config.yaml 中的 token, 没有覆盖时为空
+func (c *Config) ConfigToken() string {
+	return c.configToken
+}
+
+// 重复分享 (驱动、share_id、root_folder_id 相同) 的处理方式
+const (
+	DuplicateSkip    = "skip"    // 跳过 (默认)
+	DuplicateReport  = "report"  // 记录后仍然添加
+	DuplicateReplace = "replace" // 删除已有的存储后添加
+)
+
+// DuplicatePolicy 返回重复分享的处理方式
+func (c *Config) DuplicatePolicy() string {
+	return OrDefault(c.Duplicate, DuplicateSkip)
+}
+
+// 新建存储后列出其根目录验证, 为空或失败时的处理方式
+const (
+	VerifyOff     = "off"     // 不验证 (默认)
+	VerifyReport  = "report"  // 只在结果中报告
+	VerifyDisable = "disable" // 报告并禁用
+	VerifyDelete  = "delete"  // 报告并删除
+)
+
+// VerifyPolicy 返回新建存储后的验证方式
+func (c *Config) VerifyPolicy() string {
+	return OrDefault(c.Verify, VerifyOff)
+}
+
+// Token 保存位置
+const (
+	TokenStoreCache  = "cache"  // 保存到 token_cache.yaml (默认)
+	TokenStoreConfig = "config" // 写回 config.yaml
+)
+
 // Auth OpenList 登录认证信息
 type Auth struct {
-	Username string `yaml:"username"`
-	Password string `yaml:"password"`
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
 }
 
 // 阿里云盘配置
 type AliyunShare struct {
-	Enable       bool   `yaml:"enable"`
-	RefreshToken string `yaml:"refresh_token"`
+	Enable           bool   `yaml:"enable"`
+	RefreshToken     string `yaml:"refresh_token"`
+	RefreshTokenFile string `yaml:"refresh_token_file"`
+	File             string `yaml:"file"` // 分享文件路径, 支持通配符
+
+	// TokenSource 读取 OpenList 轮换后 refresh_token 的存储, ID 或挂载路径
+	TokenSource string `yaml:"token_source"`
+}
+
+// 阿里云盘 Open 配置, 挂载自己网盘中的文件夹
+type AliyunOpen struct {
+	Enable           bool   `yaml:"enable"`
+	RefreshToken     string `yaml:"refresh_token"`
+	RefreshTokenFile string `yaml:"refresh_token_file"`
+	ClientID         string `yaml:"client_id"` // 为空时通过在线 API 刷新 token
+	ClientSecret     string `yaml:"client_secret"`
+	ClientSecretFile string `yaml:"client_secret_file"`
+	APIURL           string `yaml:"api_url"`     // 在线 API 地址, 为空时使用 OpenList 的默认地址
+	AlipanType       string `yaml:"alipan_type"` // default (默认) 或 alipanTV
+	DriveType        string `yaml:"drive_type"`  // 默认网盘: resource (默认), backup, default
+	RemoveWay        string `yaml:"remove_way"`  // 删除方式: trash (默认) 或 delete
+	File             string `yaml:"file"`        // 挂载列表文件路径, 支持通配符
+
+	// TokenSource 读取 OpenList 轮换后 refresh_token 的存储, ID 或挂载路径
+	TokenSource string `yaml:"token_source"`
+}
+
+// 阿里云盘 Open 的网盘类型
+const (
+	AliyunDriveDefault  = "default"
+	AliyunDriveResource = "resource"
+	AliyunDriveBackup   = "backup"
+)
+
+// UseOnlineAPI 是否通过在线 API 刷新 token, 未配置 client_id 时为 true
+func (a AliyunOpen) UseOnlineAPI() bool {
+	return a.ClientID == ""
+}
+
+// PanType 返回 alipan_type, 默认 default
+func (a AliyunOpen) PanType() string {
+	return OrDefault(a.AlipanType, "default")
 }
 
-// PikPak 配置
+// DefaultDrive 返回条目未指定网盘类型时使用的网盘, 默认 resource
+func (a AliyunOpen) DefaultDrive() string {
+	return OrDefault(a.DriveType, AliyunDriveResource)
+}
+
+// RemovePolicy 返回删除方式, 默认 trash
+func (a AliyunOpen) RemovePolicy() string {
+	return OrDefault(a.RemoveWay, "trash")
+}
+
+// PikPak 账户配置
+//
+// 顶层的 username 等字段为名为 default 的账户, accounts 中可以配置更多账户
 type PikPak struct {
-	Enable       bool   `yaml:"enable"`
-	Username     string `yaml:"username"`
-	Password     string `yaml:"password"`
-	Platform     string `yaml:"platform"`
-	RefreshToken string `yaml:"refresh_token"`
+	Enable           bool            `yaml:"enable"`
+	Username         string          `yaml:"username"`
+	Password         string          `yaml:"password"`
+	PasswordFile     string          `yaml:"password_file"`
+	Platform         string          `yaml:"platform"` // 设备平台, 也是 accounts 的默认值
+	RefreshToken     string          `yaml:"refresh_token"`
+	RefreshTokenFile string          `yaml:"refresh_token_file"`
+	DeviceID         string          `yaml:"device_id"` // 为空时由 OpenList 生成
+	File             string          `yaml:"file"`      // 挂载列表文件路径, 支持通配符
+	Accounts         []PikPakAccount `yaml:"accounts"`
+}
+
+// PikPakAccount PikPak 账户
+type PikPakAccount struct {
+	Name             string `yaml:"name"` // 挂载列表中引用账户的名称
+	Username         string `yaml:"username"`
+	Password         string `yaml:"password"`
+	PasswordFile     string `yaml:"password_file"`
+	Platform         string `yaml:"platform"` // 为空时使用 pikpak.platform
+	RefreshToken     string `yaml:"refresh_token"`
+	RefreshTokenFile string `yaml:"refresh_token_file"`
+	DeviceID         string `yaml:"device_id"`
+}
+
+// PikPakDefaultAccount 顶层 PikPak 账户的名称
+const PikPakDefaultAccount = "default"
+
+// DefaultPikPakPlatform PikPak 默认设备平台
+const DefaultPikPakPlatform = "android"
+
+// AccountList 返回所有 PikPak 账户, 顶层配置了 username 时作为 default 账户排在最前;
+// 未配置平台的账户使用 pikpak.platform
+func (p PikPak) AccountList() []PikPakAccount {
+	platform := OrDefault(p.Platform, DefaultPikPakPlatform)
+	var accounts []PikPakAccount
+	if p.Username != "" {
+		accounts = append(accounts, PikPakAccount{
+			Name:         PikPakDefaultAccount,
+			Username:     p.Username,
+			Password:     p.Password,
+			Platform:     platform,
+			RefreshToken: p.RefreshToken,
+			DeviceID:     p.DeviceID,
+		})
+	}
+	for _, a := range p.Accounts {
+		a.Platform = OrDefault(a.Platform, platform)
+		accounts = append(accounts, a)
+	}
+	return accounts
 }
 
 type PikPakShare struct {
 	Enable                bool   `yaml:"enable"`
 	UseTranscodingAddress bool   `yaml:"use_transcoding_address"`
 	Platform              string `yaml:"platform"`
+	DeviceID              string `yaml:"device_id"` // 为空时由 OpenList 生成
+	File                  string `yaml:"file"`      // 分享文件路径, 支持通配符
 }
 
 // OneDrive APP 配置
@@ -43,18 +215,302 @@ type OneDriveApp struct {
 	Enable  bool     `yaml:"enable"`
 	Region  string   `yaml:"region"`
 	Tenants []Tenant `yaml:"tenants"`
+	File    string   `yaml:"file"` // 挂载列表文件路径, 支持通配符
 }
 
 // Tenant OneDrive 租户信息
 type Tenant struct {
-	ID           int    `yaml:"id"`
-	ClientID     string `yaml:"client_id"`
-	ClientSecret string `yaml:"client_secret"`
-	TenantID     string `yaml:"tenant_id"`
+	ID               int    `yaml:"id"`
+	Name             string `yaml:"name"` // 挂载列表中引用租户的名称, 不随顺序变化
+	ClientID         string `yaml:"client_id"`
+	ClientSecret     string `yaml:"client_secret"`
+	ClientSecretFile string `yaml:"client_secret_file"`
+	TenantID         string `yaml:"tenant_id"`
+	Region           string `yaml:"region"`     // 为空时使用 onedrive_app.region
+	ChunkSize        int    `yaml:"chunk_size"` // 上传分片大小 (MB), 为空时为 5
+}
+
+// Protocols 协议存储 (WebDAV、S3、SFTP、FTP、SMB) 配置, 地址与凭据写在挂载列表的每个条目中
+type Protocols struct {
+	Enable bool   `yaml:"enable"`
+	File   string `yaml:"file"` // 挂载列表文件路径, 支持通配符
+}
+
+// Local 本地存储配置, 挂载列表中的目录为 OpenList 所在主机上的路径
+type Local struct {
+	Enable bool   `yaml:"enable"`
+	File   string `yaml:"file"` // 挂载列表文件路径, 支持通配符
+}
+
+// Alias 别名存储配置, 挂载列表中每个条目把已有的多个挂载路径合并为一个
+type Alias struct {
+	Enable    bool           `yaml:"enable"`
+	File      string         `yaml:"file"` // 挂载列表文件路径, 支持通配符
+	Aggregate AliasAggregate `yaml:"aggregate"`
+}
+
+// AliasAggregate aggregate 命令把名称相同的已有存储合并为别名的规则
+type AliasAggregate struct {
+	Category   string   `yaml:"category"`    // 生成的别名所在的分类, 默认 合集
+	Name       string   `yaml:"name"`        // 别名名称模板, 默认 {name}
+	Ignore     []string `yaml:"ignore"`      // 比较名称前删除的部分, 正则表达式
+	MinDrivers int      `yaml:"min_drivers"` // 一组存储至少来自几种驱动, 默认 2
+}
+
+// 别名聚合默认值
+const (
+	DefaultAggregateCategory = "合集"
+	DefaultAggregateName     = "{name}"
+	DefaultAggregateDrivers  = 2
+)
+
+// AggregateCategory 返回生成的别名所在的分类
+func (a AliasAggregate) AggregateCategory() string {
+	return OrDefault(a.Category, DefaultAggregateCategory)
+}
+
+// NameTemplate 返回别名名称模板
+func (a AliasAggregate) NameTemplate() string {
+	return OrDefault(a.Name, DefaultAggregateName)
+}
+
+// DriverCount 返回一组存储至少需要的驱动种类数
+func (a AliasAggregate) DriverCount() int {
+	if a.MinDrivers <= 0 {
+		return DefaultAggregateDrivers
+	}
+	return a.MinDrivers
+}
+
+// AliasList 别名挂载列表, 分类 -> 名称 -> 挂载路径列表, 与 alias.yaml 格式相同
+type AliasList map[string]map[string][]string
+
+// DefaultChunkSize OneDrive 默认上传分片大小 (MB)
+const DefaultChunkSize = 5
+
+// TenantRegion 返回租户的区域, 未单独配置时使用全局区域
+func (o OneDriveApp) TenantRegion(t Tenant) string {
+	return OrDefault(t.Region, o.Region)
+}
+
+// UploadChunkSize 返回租户的上传分片大小
+func (t Tenant) UploadChunkSize() int {
+	if t.ChunkSize > 0 {
+		return t.ChunkSize
+	}
+	return DefaultChunkSize
+}
+
+// FindTenant 按名称、id、序号 (从 1 开始)、tenant_id 或 client_id 查找租户
+func (o OneDriveApp) FindTenant(ref string) (Tenant, error) {
+	for _, t := range o.Tenants {
+		if t.Name != "" && t.Name == ref {
+			return t, nil
+		}
+	}
+	if n, err := strconv.Atoi(ref); err == nil {
+		for _, t := range o.Tenants {
+			if t.ID == n {
+				return t, nil
+			}
+		}
+		if n >= 1 && n <= len(o.Tenants) {
+			return o.Tenants[n-1], nil
+		}
+	}
+	for _, t := range o.Tenants {
+		if t.TenantID == ref || t.ClientID == ref {
+			return t, nil
+		}
+	}
+	return Tenant{}, fmt.Errorf("onedrive_app.tenants 中没有租户 %s", ref)
+}
+
+// TenantRef 返回挂载列表中引用租户的写法: 有名称时为名称, 否则为序号 (从 1 开始)
+func (o OneDriveApp) TenantRef(t Tenant) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	for i, other := range o.Tenants {
+		if other.TenantID == t.TenantID && other.ClientID == t.ClientID {
+			return strconv.Itoa(i + 1)
+		}
+	}
+	return strconv.Itoa(t.ID)
+}
+
+// ShareFile 返回阿里云盘分享文件路径
+func (a AliyunShare) ShareFile() string {
+	return OrDefault(a.File, AliyunShareFile)
+}
+
+// ShareFile 返回阿里云盘 Open 挂载列表文件路径
+func (a AliyunOpen) ShareFile() string {
+	return OrDefault(a.File, AliyunOpenFile)
+}
+
+// ShareFile 返回 PikPak 分享文件路径
+func (p PikPakShare) ShareFile() string {
+	return OrDefault(p.File, PikPakShareFile)
+}
+
+// ShareFile 返回 PikPak 挂载列表文件路径
+func (p PikPak) ShareFile() string {
+	return OrDefault(p.File, PikPakFile)
+}
+
+// ShareFile 返回协议存储挂载列表文件路径
+func (p Protocols) ShareFile() string {
+	return OrDefault(p.File, ProtocolsFile)
+}
+
+// ShareFile 返回本地存储挂载列表文件路径
+func (l Local) ShareFile() string {
+	return OrDefault(l.File, LocalFile)
+}
+
+// ShareFile 返回别名存储挂载列表文件路径
+func (a Alias) ShareFile() string {
+	return OrDefault(a.File, AliasFile)
+}
+
+// ShareFile 返回 OneDrive 挂载列表文件路径
+func (o OneDriveApp) ShareFile() string {
+	return OrDefault(o.File, OneDriveAppFile)
+}
+
+// placeholders config.yaml 模板中表示未填写的占位值
+var placeholders = map[string]bool{
+	"OPENLIST_URL":             true,
+	"OPENLIST_TOKEN":           true,
+	"USERNAME":                 true,
+	"PASSWORD":                 true,
+	"ALI_YUNPAN_REFRESH_TOKEN": true,
+	"ALI_OPEN_REFRESH_TOKEN":   true,
+	"PIKPAK_USERNAME":          true,
+	"PIKPAK_PASSWORD":          true,
+	"CLIENT_ID":                true,
+	"CLIENT_SECRET":            true,
+	"TENANT_ID":                true,
+}
+
+// IsUnset 检查值是否未填写: 为空或为模板中的占位值
+func IsUnset(value string) bool {
+	return value == "" || placeholders[value]
+}
+
+// OrDefault 值为空时返回默认值
+func OrDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// Copy 从其他实例复制存储的配置
+type Copy struct {
+	DriversAllow    []string      `yaml:"drivers_allow"`    // 仅复制这些驱动, 为空表示全部
+	DriversDeny     []string      `yaml:"drivers_deny"`     // 不复制这些驱动
+	PathRewrite     []PathRewrite `yaml:"path_rewrite"`     // 挂载路径前缀改写, 按顺序匹配第一条
+	IncludeDisabled bool          `yaml:"include_disabled"` // 是否复制已禁用的存储
+}
+
+// MountPath 挂载路径生成规则
+type MountPath struct {
+	Template      string `yaml:"template"`        // 路径模板, 默认 /{category}/{name}
+	SlashReplace  string `yaml:"slash_replace"`   // 分类和名称中 / 与 \ 的替换字符, 默认 _
+	KeepFullWidth bool   `yaml:"keep_full_width"` // 保留全角字母数字和符号, 默认转为半角
+	Collision     string `yaml:"collision"`       // 路径冲突时: skip 跳过 (默认), suffix 加序号, fail 中止
+}
+
+// 挂载路径冲突处理方式
+const (
+	CollisionSkip   = "skip"
+	CollisionSuffix = "suffix"
+	CollisionFail   = "fail"
+)
+
+// DefaultMountPathTemplate 默认挂载路径模板
+const DefaultMountPathTemplate = "/{category}/{name}"
+
+// PathTemplate 返回挂载路径模板
+func (m MountPath) PathTemplate() string {
+	return OrDefault(m.Template, DefaultMountPathTemplate)
+}
+
+// SlashReplacement 返回 / 的替换字符
+func (m MountPath) SlashReplacement() string {
+	return OrDefault(m.SlashReplace, "_")
+}
+
+// CollisionMode 返回冲突处理方式
+func (m MountPath) CollisionMode() string {
+	return OrDefault(m.Collision, CollisionSkip)
+}
+
+// PathRewrite 挂载路径前缀改写规则
+type PathRewrite struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// IsProtected 检查挂载路径是否受 protected_paths 保护
+//
+// 条目为挂载路径时同时保护其下的所有存储, 也可以使用通配符 (* 不跨越 /)
+func (c *Config) IsProtected(mountPath string) bool {
+	for _, p := range c.ProtectedPaths {
+		prefix := strings.TrimSuffix(p, "/")
+		if mountPath == prefix || strings.HasPrefix(mountPath, prefix+"/") {
+			return true
+		}
+		if ok, _ := path.Match(p, mountPath); ok {
+			return true
+		}
+	}
+	return false
 }
 
 // ShareList 分享链接列表 (用于 aliyun 和 pikpak)
 type ShareList map[string]map[string]string
 
-// OneDriveList OneDrive 应用列表
+// OneDriveList OneDrive 应用列表的列表形式: 分类对应 tid:email[:path] 条目的列表
 type OneDriveList map[string][]string
+
+// ShareList 转换为 分类: {名称: 条目} 的形式, 名称由 OneDriveListName 生成,
+// 同一分类下生成的名称重复时返回错误
+func (list OneDriveList) ShareList() (ShareList, error) {
+	shares := make(ShareList, len(list))
+	for category, values := range list {
+		shares[category] = make(map[string]string, len(values))
+		for _, value := range values {
+			name, err := OneDriveListName(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", category, err)
+			}
+			if old, ok := shares[category][name]; ok {
+				return nil, fmt.Errorf("%s: %s 与 %s 生成的名称 %s 重复", category, value, old, name)
+			}
+			shares[category][name] = value
+		}
+	}
+	return shares, nil
+}
+
+// OneDriveListName 由列表条目 tid:email[:path] 生成挂载名称:
+// 邮箱 @ 前的部分, 路径不是根目录时再加上 "-" 和路径的最后一段
+func OneDriveListName(value string) (string, error) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) < 2 {
+		return "", fmt.Errorf("列表条目 %q 格式应为 tid:email[:path]", value)
+	}
+	local, _, ok := strings.Cut(parts[1], "@")
+	if !ok || local == "" {
+		return "", fmt.Errorf("列表条目 %q 中的邮箱无效", value)
+	}
+	if len(parts) == 3 {
+		if dir := path.Clean("/" + parts[2]); dir != "/" {
+			return local + "-" + path.Base(dir), nil
+		}
+	}
+	return local, nil
+}
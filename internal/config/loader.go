@@ -5,7 +5,12 @@ import (
 	"embed"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -16,13 +21,22 @@ var templates embed.FS
 const (
 	ConfigFile      = "config.yaml"
 	AliyunShareFile = "aliyun_share.yaml"
+	AliyunOpenFile  = "aliyun_open.yaml"
 	PikPakShareFile = "pikpak_share.yaml"
+	PikPakFile      = "pikpak.yaml"
 	OneDriveAppFile = "onedrive_app.yaml"
+	ProtocolsFile   = "storages.yaml"
+	LocalFile       = "local.yaml"
+	AliasFile       = "alias.yaml"
 )
 
 // Loader 配置加载器
 type Loader struct {
-	workDir string
+	workDir         string
+	configFile      string
+	passphrase      PassphraseFunc
+	passphraseValue string
+	secrets         map[string]string
 }
 
 // NewLoader 创建配置加载器
@@ -30,13 +44,102 @@ func NewLoader(workDir string) *Loader {
 	if workDir == "" {
 		workDir = "."
 	}
-	return &Loader{workDir: workDir}
+	return &Loader{workDir: workDir, configFile: ConfigFile}
 }
 
-// LoadConfig 加载主配置文件
+// SetConfigFile 指定配置文件路径, 相对路径基于工作目录
+func (l *Loader) SetConfigFile(path string) {
+	if path == "" {
+		path = ConfigFile
+	}
+	l.configFile = path
+}
+
+// ConfigPath 返回配置文件完整路径
+func (l *Loader) ConfigPath() string {
+	return l.filePath(l.configFile)
+}
+
+// WorkDir 返回工作目录
+func (l *Loader) WorkDir() string {
+	return l.workDir
+}
+
+// LoadConfig 加载主配置文件 (顶层默认实例)
 func (l *Loader) LoadConfig() (*Config, error) {
-	path := l.filePath(ConfigFile)
-	data, err := os.ReadFile(path)
+	return l.LoadProfile("")
+}
+
+// LoadProfile 加载指定实例的配置
+//
+// 命名实例继承顶层配置, 只需填写不同的字段
+func (l *Loader) LoadProfile(name string) (*Config, error) {
+	base, err := l.readConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := *base
+	if name != "" {
+		node, ok := base.Profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("实例 %s 不存在", name)
+		}
+		// token 属于具体实例, 不从顶层继承
+		cfg.Token = ""
+		cfg.TokenFile = ""
+		if err := node.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("解析实例 %s 失败: %w", name, err)
+		}
+		var own Config
+		if err := node.Decode(&own); err != nil {
+			return nil, fmt.Errorf("解析实例 %s 失败: %w", name, err)
+		}
+		dropInheritedFiles(&cfg, &own)
+		cfg.Profiles = base.Profiles
+		cfg.Profile = name
+	}
+
+	if err := l.resolveSecrets(&cfg); err != nil {
+		return nil, fmt.Errorf("解析敏感字段失败: %w", err)
+	}
+	cfg.loader = l
+
+	// 默认的 token_store 下刷新后的 token 只写入缓存, 缓存优先于配置中的 token;
+	// 写回 config.yaml 时只在配置中未填写 token 时使用缓存
+	if cfg.TokenStore != TokenStoreConfig || IsUnset(cfg.Token) {
+		cache, err := l.loadTokenCache()
+		if err != nil {
+			return nil, err
+		}
+		if token := cache[cfg.URL]; token != "" && token != cfg.Token {
+			if !IsUnset(cfg.Token) {
+				cfg.configToken = cfg.Token
+			}
+			cfg.Token = token
+		}
+	}
+	return &cfg, nil
+}
+
+// ProfileNames 返回配置中所有命名实例, 按名称排序
+func (l *Loader) ProfileNames() ([]string, error) {
+	cfg, err := l.readConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// readConfig 读取未解析敏感字段的原始配置
+func (l *Loader) readConfig() (*Config, error) {
+	data, err := os.ReadFile(l.ConfigPath())
 	if err != nil {
 		return nil, fmt.Errorf("读取配置文件失败: %w", err)
 	}
@@ -49,43 +152,114 @@ func (l *Loader) LoadConfig() (*Config, error) {
 }
 
 // LoadShareList 加载分享链接列表
+//
+// filename 可以是通配符, 匹配到的多个文件按文件名顺序合并;
+// 条目的值可以是字符串, 也可以是映射 (如 OneDrive 的结构化条目), 映射编码为 JSON 字符串;
+// 分类的内容为列表时按 OneDriveList 处理, 名称由条目生成
 func (l *Loader) LoadShareList(filename string) (ShareList, error) {
-	path := l.filePath(filename)
-	data, err := os.ReadFile(path)
+	paths, err := l.expandFiles(filename)
 	if err != nil {
-		return nil, fmt.Errorf("读取分享列表失败: %w", err)
+		return nil, err
 	}
 
-	var list ShareList
-	if err := yaml.Unmarshal(data, &list); err != nil {
-		return nil, fmt.Errorf("解析分享列表失败: %w", err)
+	merged := make(ShareList)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取分享列表失败: %w", err)
+		}
+
+		var list map[string]yaml.Node
+		if err := yaml.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("解析分享列表 %s 失败: %w", path, err)
+		}
+
+		for category, node := range list {
+			shares, err := categoryShares(category, &node)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			if merged[category] == nil {
+				merged[category] = make(map[string]string)
+			}
+			for name, value := range shares {
+				if old, ok := merged[category][name]; ok && old != value {
+					return nil, fmt.Errorf("%s: %s/%s 与其他文件中的配置冲突", path, category, name)
+				}
+				merged[category][name] = value
+			}
+		}
 	}
-	return list, nil
+	return merged, nil
 }
 
-// LoadOneDriveList 加载 OneDrive 应用列表
-func (l *Loader) LoadOneDriveList(filename string) (OneDriveList, error) {
+// categoryShares 解析一个分类的内容, 列表形式按 OneDriveList 生成名称
+func categoryShares(category string, node *yaml.Node) (map[string]string, error) {
+	switch {
+	case node.Tag == "!!null":
+		return nil, nil
+	case node.Kind == yaml.SequenceNode:
+		var values []string
+		if err := node.Decode(&values); err != nil {
+			return nil, fmt.Errorf("%s: 列表条目应为字符串", category)
+		}
+		shares, err := OneDriveList{category: values}.ShareList()
+		if err != nil {
+			return nil, err
+		}
+		return shares[category], nil
+	case node.Kind != yaml.MappingNode:
+		return nil, fmt.Errorf("%s: 内容应为 名称: 链接 的映射或条目列表", category)
+	}
+
+	var nodes map[string]yaml.Node
+	if err := node.Decode(&nodes); err != nil {
+		return nil, fmt.Errorf("%s: %w", category, err)
+	}
+	shares := make(map[string]string, len(nodes))
+	for name, n := range nodes {
+		value, err := shareValue(&n)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", category, name, err)
+		}
+		shares[name] = value
+	}
+	return shares, nil
+}
+
+// expandFiles 展开文件名中的通配符, 没有匹配时返回错误
+func (l *Loader) expandFiles(filename string) ([]string, error) {
 	path := l.filePath(filename)
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("读取 OneDrive 列表失败: %w", err)
+	if !hasGlobMeta(filename) {
+		return []string{path}, nil
 	}
 
-	var list OneDriveList
-	if err := yaml.Unmarshal(data, &list); err != nil {
-		return nil, fmt.Errorf("解析 OneDrive 列表失败: %w", err)
+	paths, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("无效的文件通配符 %s: %w", filename, err)
 	}
-	return list, nil
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("没有匹配 %s 的文件", filename)
+	}
+	sort.Strings(paths)
+	return paths, nil
 }
 
-// SaveConfig 保存配置文件
+// SaveConfig 保存刷新后的 token
+//
+// 默认写入权限为 0600 的 token 缓存, 不改动用户编辑的 config.yaml;
+// token_store 为 config 时才写回配置文件
 func (l *Loader) SaveConfig(cfg *Config) error {
-	path := l.filePath(ConfigFile)
-	data, err := yaml.Marshal(cfg)
-	if err != nil {
-		return fmt.Errorf("序列化配置失败: %w", err)
+	if cfg.TokenStore != TokenStoreConfig {
+		return l.saveTokenCache(cfg.URL, cfg.Token)
 	}
-	return os.WriteFile(path, data, 0644)
+
+	// 只修改 token 字段, 其余内容 (含注释与未解析的密钥引用) 原样保留
+	path := []string{"token"}
+	if cfg.Profile != "" {
+		path = []string{"profiles", cfg.Profile, "token"}
+	}
+	return l.SetConfigValue(path, cfg.Token)
 }
 
 // SaveShareList 保存分享链接列表到文件
@@ -98,47 +272,163 @@ func (l *Loader) SaveShareList(filename string, list ShareList) error {
 	return os.WriteFile(path, data, 0644)
 }
 
-// FileExists 检查文件是否存在
+// SaveAliasList 保存别名挂载列表
+func (l *Loader) SaveAliasList(filename string, list AliasList) error {
+	data, err := yaml.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("序列化别名列表失败: %w", err)
+	}
+	return os.WriteFile(l.filePath(filename), data, 0644)
+}
+
+// FileExists 检查文件是否存在, 通配符至少匹配一个文件即视为存在
 func (l *Loader) FileExists(filename string) bool {
-	path := l.filePath(filename)
-	_, err := os.Stat(path)
-	return err == nil
+	if hasGlobMeta(filename) {
+		paths, err := l.expandFiles(filename)
+		return err == nil && len(paths) > 0
+	}
+	return fileExists(l.filePath(filename))
 }
 
 // GenerateTemplate 生成模板文件
 func (l *Loader) GenerateTemplate(filename string) error {
-	templatePath := "templates/" + filename
-	data, err := templates.ReadFile(templatePath)
+	return l.GenerateTemplateAs(filename, filename)
+}
+
+// GenerateTemplateAs 以指定模板生成文件到 dest, dest 相对路径基于工作目录
+func (l *Loader) GenerateTemplateAs(template, dest string) error {
+	if hasGlobMeta(dest) {
+		return fmt.Errorf("%s 包含通配符, 无法生成模板", dest)
+	}
+
+	data, err := templates.ReadFile("templates/" + template)
 	if err != nil {
 		return fmt.Errorf("读取模板失败: %w", err)
 	}
 
-	path := l.filePath(filename)
+	path := l.filePath(dest)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
 	return os.WriteFile(path, data, 0644)
 }
 
-// filePath 获取完整文件路径
+// Path 返回文件完整路径, 相对路径基于工作目录
+func (l *Loader) Path(filename string) string {
+	return l.filePath(filename)
+}
+
+// filePath 获取完整文件路径, 绝对路径与 ~ 开头的路径不拼接工作目录
 func (l *Loader) filePath(filename string) string {
+	filename = expandHome(filename)
+	if filepath.IsAbs(filename) {
+		return filename
+	}
 	return filepath.Join(l.workDir, filename)
 }
 
 // Validate 验证配置有效性
 func (cfg *Config) Validate() error {
-	if cfg.URL == "" || cfg.URL == "OPENLIST_URL" {
+	if err := cfg.validate(); err != nil {
+		if cfg.Profile != "" {
+			return fmt.Errorf("实例 %s: %w", cfg.Profile, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// validate 验证单个实例的配置
+func (cfg *Config) validate() error {
+	if IsUnset(cfg.URL) {
 		return fmt.Errorf("URL 未配置")
 	}
 
-	hasAuth := cfg.Auth.Username != "" && cfg.Auth.Username != "USERNAME" &&
-		cfg.Auth.Password != "" && cfg.Auth.Password != "PASSWORD"
-	hasToken := cfg.Token != "" && cfg.Token != "OPENLIST_TOKEN"
+	hasAuth := !IsUnset(cfg.Auth.Username) && !IsUnset(cfg.Auth.Password)
+	hasToken := !IsUnset(cfg.Token)
 
 	if !hasAuth && !hasToken {
 		return fmt.Errorf("token 和用户密码至少需要配置一项")
 	}
 
+	if err := cfg.MountPath.validate(); err != nil {
+		return err
+	}
+	if err := cfg.Alias.Aggregate.validate(); err != nil {
+		return err
+	}
+
+	switch cfg.DuplicatePolicy() {
+	case DuplicateSkip, DuplicateReport, DuplicateReplace:
+	default:
+		return fmt.Errorf("未知的 duplicate: %s, 可选值: %s, %s, %s", cfg.Duplicate, DuplicateSkip, DuplicateReport, DuplicateReplace)
+	}
+
+	switch cfg.VerifyPolicy() {
+	case VerifyOff, VerifyReport, VerifyDisable, VerifyDelete:
+	default:
+		return fmt.Errorf("未知的 verify: %s, 可选值: %s, %s, %s, %s", cfg.Verify, VerifyOff, VerifyReport, VerifyDisable, VerifyDelete)
+	}
+
+	for _, p := range cfg.ProtectedPaths {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("protected_paths 中的路径必须以 / 开头: %s", p)
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("protected_paths 中的通配符无效: %s", p)
+		}
+	}
+
 	if cfg.AliyunShare.Enable {
-		if cfg.AliyunShare.RefreshToken == "" || cfg.AliyunShare.RefreshToken == "ALI_YUNPAN_REFRESH_TOKEN" {
-			return fmt.Errorf("阿里云盘分享需要配置 refresh_token")
+		if IsUnset(cfg.AliyunShare.RefreshToken) && cfg.AliyunShare.TokenSource == "" {
+			return fmt.Errorf("阿里云盘分享需要配置 refresh_token 或 token_source")
+		}
+	}
+
+	if a := cfg.AliyunOpen; a.Enable {
+		if IsUnset(a.RefreshToken) && a.TokenSource == "" {
+			return fmt.Errorf("阿里云盘 Open 需要配置 refresh_token 或 token_source")
+		}
+		if a.ClientID != "" && a.ClientSecret == "" {
+			return fmt.Errorf("阿里云盘 Open 配置了 client_id 时需要配置 client_secret")
+		}
+		switch a.DefaultDrive() {
+		case AliyunDriveDefault, AliyunDriveResource, AliyunDriveBackup:
+		default:
+			return fmt.Errorf("未知的 aliyun_open.drive_type: %s, 可选值: %s, %s, %s", a.DriveType, AliyunDriveResource, AliyunDriveBackup, AliyunDriveDefault)
+		}
+		switch a.PanType() {
+		case "default", "alipanTV":
+		default:
+			return fmt.Errorf("未知的 aliyun_open.alipan_type: %s, 可选值: default, alipanTV", a.AlipanType)
+		}
+		switch a.RemovePolicy() {
+		case "trash", "delete":
+		default:
+			return fmt.Errorf("未知的 aliyun_open.remove_way: %s, 可选值: trash, delete", a.RemoveWay)
+		}
+	}
+
+	if cfg.PikPak.Enable {
+		accounts := cfg.PikPak.AccountList()
+		if len(accounts) == 0 {
+			return fmt.Errorf("PikPak 需要配置账户")
+		}
+		names := make(map[string]bool)
+		for _, a := range accounts {
+			if a.Name == "" {
+				return fmt.Errorf("PikPak 账户 %s 需要配置 name", a.Username)
+			}
+			if _, err := strconv.Atoi(a.Name); err == nil || strings.Contains(a.Name, ":") {
+				return fmt.Errorf("PikPak 账户名称不能是数字或包含冒号: %s", a.Name)
+			}
+			if names[a.Name] {
+				return fmt.Errorf("PikPak 账户名称重复: %s", a.Name)
+			}
+			names[a.Name] = true
+			if IsUnset(a.Username) || IsUnset(a.Password) && a.RefreshToken == "" {
+				return fmt.Errorf("PikPak 账户 %s 需要配置 username 以及 password 或 refresh_token", a.Name)
+			}
 		}
 	}
 
@@ -146,14 +436,79 @@ func (cfg *Config) Validate() error {
 		if len(cfg.OneDriveApp.Tenants) == 0 {
 			return fmt.Errorf("OneDrive 需要配置租户信息")
 		}
+		names := make(map[string]bool)
 		for _, t := range cfg.OneDriveApp.Tenants {
-			if t.ClientID == "" || t.ClientID == "CLIENT_ID" ||
-				t.ClientSecret == "" || t.ClientSecret == "CLIENT_SECRET" ||
-				t.TenantID == "" || t.TenantID == "TENANT_ID" {
+			if IsUnset(t.ClientID) || IsUnset(t.ClientSecret) || IsUnset(t.TenantID) {
 				return fmt.Errorf("OneDrive 租户配置不完整")
 			}
+			if t.Name != "" {
+				if _, err := strconv.Atoi(t.Name); err == nil || strings.Contains(t.Name, ":") {
+					return fmt.Errorf("OneDrive 租户名称不能是数字或包含冒号: %s", t.Name)
+				}
+				if names[t.Name] {
+					return fmt.Errorf("OneDrive 租户名称重复: %s", t.Name)
+				}
+				names[t.Name] = true
+			}
+			if t.ChunkSize < 0 {
+				return fmt.Errorf("OneDrive 租户 %s 的 chunk_size 不能为负数", t.TenantID)
+			}
 		}
 	}
 
 	return nil
 }
+
+// aggregateNameVars 别名名称模板支持的变量
+var aggregateNameVars = []string{"{name}", "{category}", "{count}"}
+
+// validate 验证别名聚合规则
+func (a AliasAggregate) validate() error {
+	tmpl := a.NameTemplate()
+	if !strings.Contains(tmpl, "{name}") {
+		return fmt.Errorf("alias.aggregate.name 必须包含 {name}: %s", tmpl)
+	}
+	rest := tmpl
+	for _, v := range aggregateNameVars {
+		rest = strings.ReplaceAll(rest, v, "")
+	}
+	if strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("alias.aggregate.name 包含未知变量: %s, 可选变量: %s", tmpl, strings.Join(aggregateNameVars, " "))
+	}
+	for _, expr := range a.Ignore {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("alias.aggregate.ignore 中的正则表达式无效: %s: %w", expr, err)
+		}
+	}
+	return nil
+}
+
+// mountPathVars 挂载路径模板支持的变量
+var mountPathVars = []string{"{provider}", "{driver}", "{category}", "{name}"}
+
+// validate 验证挂载路径规则
+func (m MountPath) validate() error {
+	tmpl := m.PathTemplate()
+	if !strings.HasPrefix(tmpl, "/") {
+		return fmt.Errorf("mount_path.template 必须以 / 开头: %s", tmpl)
+	}
+	if !strings.Contains(tmpl, "{name}") {
+		return fmt.Errorf("mount_path.template 必须包含 {name}: %s", tmpl)
+	}
+	rest := tmpl
+	for _, v := range mountPathVars {
+		rest = strings.ReplaceAll(rest, v, "")
+	}
+	if strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("mount_path.template 包含未知变量: %s, 可选变量: %s", tmpl, strings.Join(mountPathVars, " "))
+	}
+	if strings.ContainsAny(m.SlashReplacement(), "/\\") {
+		return fmt.Errorf("mount_path.slash_replace 不能包含 / 或 \\")
+	}
+
+	switch m.CollisionMode() {
+	case CollisionSkip, CollisionSuffix, CollisionFail:
+		return nil
+	}
+	return fmt.Errorf("未知的 mount_path.collision: %s, 可选值: %s, %s, %s", m.Collision, CollisionSkip, CollisionSuffix, CollisionFail)
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadProfileTokenCache(t *testing.T) {
+	const url = "http://127.0.0.1:5244"
+	tests := []struct {
+		name        string
+		config      string
+		cache       string
+		token       string
+		configToken string
+	}{
+		{
+			name:   "cache overrides expired config token",
+			config: "url: " + url + "\ntoken: handset\n",
+			cache:  url + ": refreshed\n",
+			token:  "refreshed", configToken: "handset",
+		},
+		{
+			name:   "cache fills placeholder",
+			config: "url: " + url + "\ntoken: OPENLIST_TOKEN\n",
+			cache:  url + ": refreshed\n",
+			token:  "refreshed",
+		},
+		{
+			name:   "cache for another url",
+			config: "url: " + url + "\ntoken: handset\n",
+			cache:  "http://other:5244: refreshed\n",
+			token:  "handset",
+		},
+		{
+			name:   "cache equals config token",
+			config: "url: " + url + "\ntoken: same\n",
+			cache:  url + ": same\n",
+			token:  "same",
+		},
+		{
+			name:   "no cache",
+			config: "url: " + url + "\ntoken: handset\n",
+			token:  "handset",
+		},
+		{
+			name:   "token_store config keeps config token",
+			config: "url: " + url + "\ntoken: handset\ntoken_store: config\n",
+			cache:  url + ": stale\n",
+			token:  "handset",
+		},
+		{
+			name:   "token_store config without token uses cache",
+			config: "url: " + url + "\ntoken_store: config\n",
+			cache:  url + ": refreshed\n",
+			token:  "refreshed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, ConfigFile), tt.config)
+			if tt.cache != "" {
+				writeFile(t, filepath.Join(dir, TokenCacheFile), tt.cache)
+			}
+
+			cfg, err := NewLoader(dir).LoadProfile("")
+			if err != nil {
+				t.Fatalf("LoadProfile error: %v", err)
+			}
+			if cfg.Token != tt.token || cfg.ConfigToken() != tt.configToken {
+				t.Errorf("token = %q, config token = %q, want %q, %q", cfg.Token, cfg.ConfigToken(), tt.token, tt.configToken)
+			}
+		})
+	}
+}
+
+func TestSaveConfigTokenIsLoaded(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ConfigFile), "url: http://127.0.0.1:5244\ntoken: handset\n")
+	loader := NewLoader(dir)
+
+	cfg, err := loader.LoadProfile("")
+	if err != nil {
+		t.Fatalf("LoadProfile error: %v", err)
+	}
+	cfg.Token = "refreshed"
+	if err := loader.SaveConfig(cfg); err != nil {
+		t.Fatalf("SaveConfig error: %v", err)
+	}
+
+	cfg, err = loader.LoadProfile("")
+	if err != nil {
+		t.Fatalf("LoadProfile error: %v", err)
+	}
+	if cfg.Token != "refreshed" {
+		t.Errorf("token after refresh = %q, want refreshed", cfg.Token)
+	}
+}
+
+// writeFile 写入测试文件
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
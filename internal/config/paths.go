@@ -0,0 +1,81 @@
+// Package config 处理应用程序配置
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AppName 用于默认配置目录的应用名
+const AppName = "openlist_batch"
+
+// DefaultConfigDir 返回 XDG 风格的默认配置目录
+//
+// 优先 $XDG_CONFIG_HOME/openlist_batch, 其次 ~/.config/openlist_batch
+func DefaultConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, AppName)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".config", AppName)
+	}
+	return ""
+}
+
+// ResolveLocation 确定工作目录和配置文件路径
+//
+// 查找顺序:
+//  1. -workdir 指定的目录, -config 指定的文件
+//  2. 只指定 -config 时, 以配置文件所在目录为工作目录
+//  3. 当前目录存在 config.yaml 时使用当前目录
+//  4. 默认配置目录存在 config.yaml 时使用默认配置目录
+//  5. 都不存在时使用当前目录 (生成模板)
+func ResolveLocation(configFlag, workDirFlag string) (workDir, configFile string) {
+	configFile = expandHome(configFlag)
+	workDir = expandHome(workDirFlag)
+
+	if workDir == "" && configFile != "" {
+		workDir = filepath.Dir(configFile)
+	}
+	if workDir == "" {
+		workDir = "."
+		if !fileExists(ConfigFile) {
+			if dir := DefaultConfigDir(); dir != "" && fileExists(filepath.Join(dir, ConfigFile)) {
+				workDir = dir
+			}
+		}
+	}
+
+	if configFile == "" {
+		configFile = filepath.Join(workDir, ConfigFile)
+	}
+	// 配置文件路径按当前目录解析, 避免再次拼接工作目录
+	if abs, err := filepath.Abs(configFile); err == nil {
+		configFile = abs
+	}
+	return workDir, configFile
+}
+
+// expandHome 展开路径开头的 ~
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// fileExists 检查文件是否存在
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// hasGlobMeta 检查路径中是否包含通配符
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
@@ -0,0 +1,370 @@
+// Package config 处理应用程序配置
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SecretsFile    = "secrets.enc"
+	TokenCacheFile = "token_cache.yaml"
+
+	// PassphraseEnv 加密密钥文件口令的环境变量
+	PassphraseEnv = "OPENLIST_BATCH_PASSPHRASE"
+
+	// secretPrefix 引用加密密钥文件中条目的前缀, 如 secret:openlist_password
+	secretPrefix = "secret:"
+
+	pbkdf2Iterations = 600000
+)
+
+// envPattern 匹配 ${VAR} 形式的环境变量引用
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// secretsEnvelope 加密密钥文件的存储格式
+type secretsEnvelope struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// PassphraseFunc 获取加密密钥文件口令
+type PassphraseFunc func() (string, error)
+
+// SetPassphraseFunc 设置口令获取方式, 未设置时仅读取环境变量
+func (l *Loader) SetPassphraseFunc(fn PassphraseFunc) {
+	l.passphrase = fn
+}
+
+// getPassphrase 获取口令, 环境变量优先, 获取后缓存
+func (l *Loader) getPassphrase() (string, error) {
+	if l.passphraseValue != "" {
+		return l.passphraseValue, nil
+	}
+	p := os.Getenv(PassphraseEnv)
+	if p == "" {
+		if l.passphrase == nil {
+			return "", fmt.Errorf("未设置环境变量 %s", PassphraseEnv)
+		}
+		var err error
+		if p, err = l.passphrase(); err != nil {
+			return "", err
+		}
+	}
+	l.passphraseValue = p
+	return p, nil
+}
+
+// LoadSecrets 解密并读取密钥文件, 文件不存在时返回空集合
+func (l *Loader) LoadSecrets() (map[string]string, error) {
+	if l.secrets != nil {
+		return l.secrets, nil
+	}
+
+	passphrase, err := l.getPassphrase()
+	if err != nil {
+		return nil, fmt.Errorf("获取密钥文件口令失败: %w", err)
+	}
+
+	data, err := os.ReadFile(l.filePath(SecretsFile))
+	if os.IsNotExist(err) {
+		l.secrets = make(map[string]string)
+		return l.secrets, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+
+	var env secretsEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("解析密钥文件失败: %w", err)
+	}
+
+	gcm, err := newGCM(passphrase, env.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := gcm.Open(nil, env.Nonce, env.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("解密密钥文件失败, 口令错误或文件已损坏")
+	}
+
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("解析密钥内容失败: %w", err)
+	}
+
+	l.secrets = secrets
+	return secrets, nil
+}
+
+// SetSecret 写入或更新加密密钥文件中的条目
+func (l *Loader) SetSecret(name, value string) error {
+	secrets, err := l.LoadSecrets()
+	if err != nil {
+		return err
+	}
+	secrets[name] = value
+
+	passphrase, err := l.getPassphrase()
+	if err != nil {
+		return fmt.Errorf("获取密钥文件口令失败: %w", err)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("生成盐值失败: %w", err)
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("生成随机数失败: %w", err)
+	}
+
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("序列化密钥失败: %w", err)
+	}
+
+	data, err := json.Marshal(secretsEnvelope{
+		Salt:  salt,
+		Nonce: nonce,
+		Data:  gcm.Seal(nil, nonce, plain, nil),
+	})
+	if err != nil {
+		return fmt.Errorf("序列化密钥文件失败: %w", err)
+	}
+
+	l.secrets = secrets
+	return os.WriteFile(l.filePath(SecretsFile), data, 0600)
+}
+
+// newGCM 由口令派生 AES-256-GCM 密钥
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("派生密钥失败: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("初始化加密失败: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// ResolveSecret 按加载该配置的 Loader 解析配置以外 (如协议存储条目) 的敏感字段,
+// 规则同 resolveSecret; 配置不是由 Loader 加载时原样返回 value
+func (cfg *Config) ResolveSecret(value, file string) (string, error) {
+	if cfg.loader == nil {
+		if file != "" {
+			return "", fmt.Errorf("无法读取 %s", file)
+		}
+		return value, nil
+	}
+	return cfg.loader.resolveSecret(value, file)
+}
+
+// resolveSecret 解析单个敏感字段
+//
+// 优先级: *_file 指定的文件 > secret:NAME 引用 > ${VAR} 环境变量 > 原值
+func (l *Loader) resolveSecret(value, file string) (string, error) {
+	if file != "" {
+		data, err := os.ReadFile(l.filePath(file))
+		if err != nil {
+			return "", fmt.Errorf("读取密钥文件 %s 失败: %w", file, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	if name, ok := strings.CutPrefix(value, secretPrefix); ok {
+		secrets, err := l.LoadSecrets()
+		if err != nil {
+			return "", err
+		}
+		secret, ok := secrets[name]
+		if !ok {
+			return "", fmt.Errorf("密钥文件中不存在 %s", name)
+		}
+		return secret, nil
+	}
+
+	var missing []string
+	resolved := envPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envPattern.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("环境变量未设置: %s", strings.Join(missing, ", "))
+	}
+	return resolved, nil
+}
+
+// secretField 待解析的敏感字段
+type secretField struct {
+	name  string
+	value *string
+	file  string
+}
+
+// resolveSecrets 解析配置中所有敏感字段
+func (l *Loader) resolveSecrets(cfg *Config) error {
+	fields := []secretField{
+		{"url", &cfg.URL, ""},
+		{"auth.username", &cfg.Auth.Username, ""},
+		{"auth.password", &cfg.Auth.Password, cfg.Auth.PasswordFile},
+		{"token", &cfg.Token, cfg.TokenFile},
+		{"aliyun_share.refresh_token", &cfg.AliyunShare.RefreshToken, cfg.AliyunShare.RefreshTokenFile},
+		{"aliyun_open.refresh_token", &cfg.AliyunOpen.RefreshToken, cfg.AliyunOpen.RefreshTokenFile},
+		{"aliyun_open.client_secret", &cfg.AliyunOpen.ClientSecret, cfg.AliyunOpen.ClientSecretFile},
+	}
+	fields = append(fields,
+		secretField{"pikpak.password", &cfg.PikPak.Password, cfg.PikPak.PasswordFile},
+		secretField{"pikpak.refresh_token", &cfg.PikPak.RefreshToken, cfg.PikPak.RefreshTokenFile},
+	)
+	for i := range cfg.PikPak.Accounts {
+		a := &cfg.PikPak.Accounts[i]
+		fields = append(fields,
+			secretField{fmt.Sprintf("pikpak.accounts[%d].password", i), &a.Password, a.PasswordFile},
+			secretField{fmt.Sprintf("pikpak.accounts[%d].refresh_token", i), &a.RefreshToken, a.RefreshTokenFile},
+		)
+	}
+	for i := range cfg.OneDriveApp.Tenants {
+		t := &cfg.OneDriveApp.Tenants[i]
+		name := fmt.Sprintf("onedrive_app.tenants[%d].client_secret", i)
+		fields = append(fields, secretField{name, &t.ClientSecret, t.ClientSecretFile})
+	}
+
+	for _, f := range fields {
+		v, err := l.resolveSecret(*f.value, f.file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+		*f.value = v
+	}
+	return nil
+}
+
+// SaveAliyunRefreshToken 把轮换后的阿里云盘 refresh_token 写回其来源
+//
+// 依次为 refresh_token_file 指定的文件、secret:NAME 引用的密钥文件和 config.yaml 中的
+// refresh_token 字段 (实例自己填写时写入实例下); 引用环境变量时无法写回, 返回错误
+func (l *Loader) SaveAliyunRefreshToken(cfg *Config, token string) error {
+	return l.saveRefreshToken(cfg, "aliyun_share", cfg.AliyunShare.RefreshTokenFile, token,
+		func(c *Config) string { return c.AliyunShare.RefreshToken })
+}
+
+// SaveAliyunOpenRefreshToken 把轮换后的阿里云盘 Open refresh_token 写回其来源,
+// 规则同 SaveAliyunRefreshToken
+func (l *Loader) SaveAliyunOpenRefreshToken(cfg *Config, token string) error {
+	return l.saveRefreshToken(cfg, "aliyun_open", cfg.AliyunOpen.RefreshTokenFile, token,
+		func(c *Config) string { return c.AliyunOpen.RefreshToken })
+}
+
+// saveRefreshToken 把 refresh_token 写回 file、密钥文件或 config.yaml 中 section 下的字段,
+// field 从未解析的配置中取出该字段的原值
+func (l *Loader) saveRefreshToken(cfg *Config, section, file, token string, field func(*Config) string) error {
+	if file != "" {
+		return os.WriteFile(l.filePath(file), []byte(token+"\n"), 0600)
+	}
+
+	base, err := l.readConfig()
+	if err != nil {
+		return err
+	}
+	raw := field(base)
+	path := []string{section, "refresh_token"}
+	if cfg.Profile != "" {
+		var own Config
+		if node, ok := base.Profiles[cfg.Profile]; ok {
+			if err := node.Decode(&own); err != nil {
+				return fmt.Errorf("解析实例 %s 失败: %w", cfg.Profile, err)
+			}
+		}
+		if v := field(&own); v != "" {
+			raw = v
+			path = []string{"profiles", cfg.Profile, section, "refresh_token"}
+		}
+	}
+
+	if name, ok := strings.CutPrefix(raw, secretPrefix); ok {
+		return l.SetSecret(name, token)
+	}
+	if envPattern.MatchString(raw) {
+		return fmt.Errorf("refresh_token 引用了环境变量, 无法写回, 请手动更新")
+	}
+	return l.SetConfigValue(path, token)
+}
+
+// dropInheritedFiles 实例直接填写了敏感字段时, 忽略从顶层继承的 *_file
+func dropInheritedFiles(cfg, own *Config) {
+	if own.Auth.Password != "" && own.Auth.PasswordFile == "" {
+		cfg.Auth.PasswordFile = ""
+	}
+	if own.AliyunShare.RefreshToken != "" && own.AliyunShare.RefreshTokenFile == "" {
+		cfg.AliyunShare.RefreshTokenFile = ""
+	}
+	if own.AliyunOpen.RefreshToken != "" && own.AliyunOpen.RefreshTokenFile == "" {
+		cfg.AliyunOpen.RefreshTokenFile = ""
+	}
+	if own.AliyunOpen.ClientSecret != "" && own.AliyunOpen.ClientSecretFile == "" {
+		cfg.AliyunOpen.ClientSecretFile = ""
+	}
+}
+
+// loadTokenCache 读取 token 缓存, 以 OpenList 地址为键
+func (l *Loader) loadTokenCache() (map[string]string, error) {
+	cache := make(map[string]string)
+	data, err := os.ReadFile(l.filePath(TokenCacheFile))
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 token 缓存失败: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("解析 token 缓存失败: %w", err)
+	}
+	return cache, nil
+}
+
+// saveTokenCache 保存 token 到缓存文件 (权限 0600)
+func (l *Loader) saveTokenCache(url, token string) error {
+	cache, err := l.loadTokenCache()
+	if err != nil {
+		return err
+	}
+	cache[url] = token
+
+	data, err := yaml.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("序列化 token 缓存失败: %w", err)
+	}
+
+	path := l.filePath(TokenCacheFile)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	// WriteFile 不会修改已存在文件的权限
+	return os.Chmod(path, 0600)
+}
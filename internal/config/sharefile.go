@@ -0,0 +1,203 @@
+// Package config 处理应用程序配置
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ShareEntry 分享文件中的一条记录及其位置
+type ShareEntry struct {
+	File     string
+	Line     int
+	Category string
+	Name     string
+	Value    string
+}
+
+// Label 显示用的条目名称
+func (e ShareEntry) Label() string {
+	return e.Category + "/" + e.Name
+}
+
+// FileError 分享文件中某一行的问题, Line 为 0 表示整个文件
+type FileError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *FileError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// LoadShareEntries 按文件中的顺序读取分享条目及其行号
+//
+// 与 LoadShareList 不同, 文件结构上的问题 (无法解析、值不是字符串、重复的键或生成的名称、
+// 与其他文件冲突) 不会中止读取, 而是作为 FileError 返回; 只有通配符无效或
+// 没有匹配的文件时返回 error
+func (l *Loader) LoadShareEntries(filename string) ([]ShareEntry, []*FileError, error) {
+	paths, err := l.expandFiles(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var entries []ShareEntry
+	var problems []*FileError
+	first := make(map[string]ShareEntry)
+
+	for _, path := range paths {
+		fileEntries, fileProblems := readShareEntries(path)
+		problems = append(problems, fileProblems...)
+
+		for _, entry := range fileEntries {
+			key := entry.Category + "\x00" + entry.Name
+			if prev, ok := first[key]; ok && prev.File != entry.File {
+				if prev.Value != entry.Value {
+					problems = append(problems, &FileError{entry.File, entry.Line,
+						fmt.Sprintf("%s 与 %s:%d 中的配置冲突", entry.Label(), prev.File, prev.Line)})
+				}
+				continue
+			}
+			first[key] = entry
+			entries = append(entries, entry)
+		}
+	}
+	return entries, problems, nil
+}
+
+// readShareEntries 读取单个分享文件
+func readShareEntries(path string) ([]ShareEntry, []*FileError) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, []*FileError{{File: path, Msg: fmt.Sprintf("读取失败: %v", err)}}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, []*FileError{{File: path, Msg: fmt.Sprintf("解析失败: %v", err)}}
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, []*FileError{{path, root.Line, "顶层应为 分类: {名称: 链接} 的映射"}}
+	}
+
+	var entries []ShareEntry
+	var problems []*FileError
+	report := func(line int, format string, args ...any) {
+		problems = append(problems, &FileError{path, line, fmt.Sprintf(format, args...)})
+	}
+
+	categories := make(map[string]int)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		category := key.Value
+		if line, dup := categories[category]; dup {
+			report(key.Line, "分类 %s 重复, 第一次出现在第 %d 行", category, line)
+			continue
+		}
+		categories[category] = key.Line
+
+		if value.Tag == "!!null" {
+			continue
+		}
+		names := make(map[string]int)
+		if value.Kind == yaml.SequenceNode {
+			// 列表形式 (OneDriveList), 名称由条目生成
+			for _, item := range value.Content {
+				if item.Kind != yaml.ScalarNode || item.Tag == "!!null" {
+					report(item.Line, "%s: 列表条目应为字符串", category)
+					continue
+				}
+				name, err := OneDriveListName(item.Value)
+				if err != nil {
+					report(item.Line, "%s: %v", category, err)
+					continue
+				}
+				if line, dup := names[name]; dup {
+					report(item.Line, "%s/%s 重复, 第一次出现在第 %d 行", category, name, line)
+					continue
+				}
+				names[name] = item.Line
+				entries = append(entries, ShareEntry{
+					File:     path,
+					Line:     item.Line,
+					Category: category,
+					Name:     name,
+					Value:    item.Value,
+				})
+			}
+			continue
+		}
+		if value.Kind != yaml.MappingNode {
+			report(value.Line, "分类 %s 的内容应为 名称: 链接 的映射或条目列表", category)
+			continue
+		}
+
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			nameNode, linkNode := value.Content[j], value.Content[j+1]
+			name := nameNode.Value
+			if line, dup := names[name]; dup {
+				report(nameNode.Line, "%s/%s 重复, 第一次出现在第 %d 行", category, name, line)
+				continue
+			}
+			names[name] = nameNode.Line
+
+			value, err := shareValue(linkNode)
+			if err != nil {
+				report(linkNode.Line, "%s/%s: %v", category, name, err)
+				continue
+			}
+			entries = append(entries, ShareEntry{
+				File:     path,
+				Line:     nameNode.Line,
+				Category: category,
+				Name:     name,
+				Value:    value,
+			})
+		}
+	}
+	return entries, problems
+}
+
+// shareValue 返回条目的值: 字符串原样返回, 映射和字符串列表 (如别名的路径列表)
+// 编码为 JSON 字符串交给提供商解析
+func shareValue(node *yaml.Node) (string, error) {
+	var v any
+	switch {
+	case node.Kind == yaml.MappingNode:
+		var m map[string]any
+		if err := node.Decode(&m); err != nil {
+			return "", fmt.Errorf("内容无法解析: %w", err)
+		}
+		v = m
+	case node.Kind == yaml.SequenceNode:
+		var list []string
+		if err := node.Decode(&list); err != nil {
+			return "", fmt.Errorf("列表的每一项应为字符串")
+		}
+		v = list
+	case node.Kind != yaml.ScalarNode:
+		return "", fmt.Errorf("值应为字符串、映射或列表")
+	case node.Tag == "!!null" || node.Value == "":
+		return "", fmt.Errorf("值为空")
+	default:
+		return node.Value, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("内容无法编码: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,198 @@
+// Package config 处理应用程序配置
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DirectoryUser 租户用户列表导出中的一个用户
+type DirectoryUser struct {
+	Email    string // mail, 为空时为 userPrincipalName
+	Name     string // 显示名称
+	Enabled  bool
+	Licensed bool // 导出中没有许可证信息时视为已分配
+}
+
+// graphUser Graph /users 返回的用户
+type graphUser struct {
+	Mail              string `json:"mail"`
+	UserPrincipalName string `json:"userPrincipalName"`
+	DisplayName       string `json:"displayName"`
+	AccountEnabled    *bool  `json:"accountEnabled"`
+	AssignedLicenses  *[]any `json:"assignedLicenses"`
+}
+
+// LoadDirectoryUsers 读取租户用户列表导出
+//
+// 支持 Graph /users 的 JSON 响应 ({"value": [...]} 或用户数组, 可用 $select 加上
+// accountEnabled 和 assignedLicenses), 以及 Azure AD / Microsoft 365 管理中心导出的 CSV
+func (l *Loader) LoadDirectoryUsers(filename string) ([]DirectoryUser, error) {
+	data, err := os.ReadFile(l.filePath(filename))
+	if err != nil {
+		return nil, fmt.Errorf("读取用户列表失败: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var users []DirectoryUser
+	switch trimmed := bytes.TrimSpace(data); {
+	case len(trimmed) == 0:
+		return nil, fmt.Errorf("用户列表 %s 为空", filename)
+	case trimmed[0] == '{' || trimmed[0] == '[':
+		users, err = parseGraphUsers(trimmed)
+	default:
+		users, err = parseUserCSV(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析用户列表 %s 失败: %w", filename, err)
+	}
+	return users, nil
+}
+
+// parseGraphUsers 解析 Graph /users 的 JSON
+func parseGraphUsers(data []byte) ([]DirectoryUser, error) {
+	var list []graphUser
+	if data[0] == '{' {
+		var page struct {
+			Value []graphUser `json:"value"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, err
+		}
+		list = page.Value
+	} else if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	users := make([]DirectoryUser, 0, len(list))
+	for _, u := range list {
+		users = append(users, DirectoryUser{
+			Email:    OrDefault(u.Mail, u.UserPrincipalName),
+			Name:     u.DisplayName,
+			Enabled:  u.AccountEnabled == nil || *u.AccountEnabled,
+			Licensed: u.AssignedLicenses == nil || len(*u.AssignedLicenses) > 0,
+		})
+	}
+	return users, nil
+}
+
+// parseUserCSV 解析用户导出 CSV, 按表头识别列
+//
+// Azure AD 批量下载的表头形如 "userPrincipalName [userPrincipalName] Required",
+// Microsoft 365 管理中心的表头形如 "User principal name"; 比较时忽略大小写、
+// 空格和方括号部分
+func parseUserCSV(data []byte) ([]DirectoryUser, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		if j := strings.Index(h, "["); j >= 0 {
+			h = h[:j]
+		}
+		h = strings.ToLower(strings.NewReplacer(" ", "", "_", "").Replace(h))
+		if _, ok := columns[h]; !ok {
+			columns[h] = i
+		}
+	}
+	column := func(names ...string) int {
+		for _, name := range names {
+			if i, ok := columns[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+	upn := column("userprincipalname", "username")
+	mail := column("mail", "email", "emailaddress")
+	if upn < 0 && mail < 0 {
+		return nil, fmt.Errorf("CSV 中没有 userPrincipalName 或 mail 列")
+	}
+	name := column("displayname", "name")
+	enabled := column("accountenabled")
+	blocked := column("blockcredential", "signinblocked")
+	licenses := column("licenses", "assignedlicenses", "islicensed")
+
+	var users []DirectoryUser
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		user := DirectoryUser{
+			Email:    OrDefault(field(mail), field(upn)),
+			Name:     field(name),
+			Enabled:  true,
+			Licensed: true,
+		}
+		if user.Email == "" {
+			continue
+		}
+		if v, err := strconv.ParseBool(field(enabled)); enabled >= 0 && err == nil {
+			user.Enabled = v
+		}
+		if v, err := strconv.ParseBool(field(blocked)); blocked >= 0 && err == nil {
+			user.Enabled = !v
+		}
+		if licenses >= 0 {
+			user.Licensed = csvLicensed(field(licenses))
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// csvLicensed 解析 CSV 中的许可证列: 布尔值, 或以 + 分隔的许可证名称
+func csvLicensed(value string) bool {
+	if v, err := strconv.ParseBool(value); err == nil {
+		return v
+	}
+	switch strings.ToLower(value) {
+	case "", "unlicensed", "none":
+		return false
+	}
+	return true
+}
+
+// MatchUserPatterns 检查邮箱是否匹配任意一个通配符 (不区分大小写)
+func MatchUserPatterns(patterns []string, email string) bool {
+	email = strings.ToLower(email)
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), email); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveOneDriveList 以列表形式保存 OneDrive 条目
+func (l *Loader) SaveOneDriveList(filename string, list OneDriveList) error {
+	data, err := yaml.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("序列化 OneDrive 列表失败: %w", err)
+	}
+	return os.WriteFile(l.filePath(filename), data, 0644)
+}
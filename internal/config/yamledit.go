@@ -0,0 +1,287 @@
+// Package config 处理应用程序配置
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetConfigValue 原地修改 config.yaml 中的单个标量字段
+//
+// 只替换目标值所在的字节区间, 注释、顺序和未知字段保持不变
+func (l *Loader) SetConfigValue(path []string, value string) error {
+	file := l.ConfigPath()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	updated, err := setYAMLValue(data, path, value)
+	if err != nil {
+		return fmt.Errorf("修改配置 %s 失败: %w", strings.Join(path, "."), err)
+	}
+	if bytes.Equal(updated, data) {
+		return nil
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, updated, info.Mode().Perm())
+}
+
+// setYAMLValue 在 YAML 文本中设置 path 指向的标量值
+func setYAMLValue(data []byte, path []string, value string) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("路径为空")
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("解析 YAML 失败: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("文件为空")
+	}
+
+	scalar, err := formatScalar(value)
+	if err != nil {
+		return nil, err
+	}
+
+	node := doc.Content[0]
+	var out []byte
+	for i, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s 不是映射", strings.Join(path[:i], "."))
+		}
+		if node.Style&yaml.FlowStyle != 0 {
+			return nil, fmt.Errorf("不支持修改流式映射")
+		}
+
+		keyNode, valNode := mappingEntry(node, key)
+		if keyNode == nil {
+			if i != len(path)-1 {
+				return nil, fmt.Errorf("%s 不存在", strings.Join(path[:i+1], "."))
+			}
+			out, err = insertMappingEntry(data, node, key, scalar)
+			break
+		}
+
+		if i == len(path)-1 {
+			out, err = replaceScalar(data, keyNode, valNode, scalar)
+			break
+		}
+		node = valNode
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkYAMLValue(out, path, value); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// checkYAMLValue 重新解析修改后的文本, 确认 path 指向的值就是 value
+//
+// 普通标量可以延续到下一行 (如 "token: abc" 下一行缩进的 "def"), 只替换第一行
+// 会让延续的内容拼到新值后面, 这类跨行的值在这里被拒绝
+func checkYAMLValue(data []byte, path []string, value string) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("修改后无法解析: %w", err)
+	}
+	node := doc.Content[0]
+	for _, key := range path {
+		if _, node = mappingEntry(node, key); node == nil {
+			return fmt.Errorf("修改后找不到 %s", strings.Join(path, "."))
+		}
+	}
+	var got string
+	if node.Kind != yaml.ScalarNode || node.Decode(&got) != nil || got != value {
+		return fmt.Errorf("%s 的原值跨行, 不支持原地修改", strings.Join(path, "."))
+	}
+	return nil
+}
+
+// mappingEntry 查找映射中的键值节点
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// formatScalar 把字符串格式化为单行 YAML 标量, 必要时加引号
+func formatScalar(value string) (string, error) {
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("序列化值失败: %w", err)
+	}
+	s := strings.TrimSuffix(string(out), "\n")
+	if strings.Contains(s, "\n") {
+		return "", fmt.Errorf("不支持多行值")
+	}
+	return s, nil
+}
+
+// replaceScalar 替换已有键的值
+func replaceScalar(data []byte, keyNode, valNode *yaml.Node, scalar string) ([]byte, error) {
+	if valNode.Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("%s 不是标量", keyNode.Value)
+	}
+
+	// "key:" 后没有值时, 解析器给出的位置不可靠, 直接插在冒号后
+	if valNode.Tag == "!!null" && valNode.Value == "" {
+		keyEnd, err := scalarEnd(data, keyNode)
+		if err != nil {
+			return nil, err
+		}
+		colon := bytes.IndexByte(data[keyEnd:], ':')
+		if colon < 0 {
+			return nil, fmt.Errorf("找不到 %s 的冒号", keyNode.Value)
+		}
+		pos := keyEnd + colon + 1
+		return splice(data, pos, pos, " "+scalar), nil
+	}
+
+	if valNode.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return nil, fmt.Errorf("%s 为多行文本, 不支持原地修改", keyNode.Value)
+	}
+
+	start, err := nodeOffset(data, valNode)
+	if err != nil {
+		return nil, err
+	}
+	end, err := scalarEnd(data, valNode)
+	if err != nil {
+		return nil, err
+	}
+	return splice(data, start, end, scalar), nil
+}
+
+// insertMappingEntry 在块映射的第一个键之前插入新键
+func insertMappingEntry(data []byte, mapping *yaml.Node, key, scalar string) ([]byte, error) {
+	k, err := formatScalar(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(mapping.Content) == 0 {
+		line := k + ": " + scalar + "\n"
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			line = "\n" + line
+		}
+		return append(data, line...), nil
+	}
+
+	first := mapping.Content[0]
+	start, err := nodeOffset(data, first)
+	if err != nil {
+		return nil, err
+	}
+	lineStart := bytes.LastIndexByte(data[:start], '\n') + 1
+	indent := string(data[lineStart:start])
+	if strings.TrimSpace(indent) != "" {
+		// 键与其他内容同行 (如 "- key: v"), 用空格补齐缩进
+		indent = strings.Repeat(" ", first.Column-1)
+		lineStart = start
+		return splice(data, lineStart, lineStart, k+": "+scalar+"\n"+indent), nil
+	}
+	return splice(data, lineStart, lineStart, indent+k+": "+scalar+"\n"), nil
+}
+
+// nodeOffset 把节点的行列号 (从 1 开始, 列按字符计) 换算为字节偏移
+func nodeOffset(data []byte, node *yaml.Node) (int, error) {
+	offset := 0
+	for line := 1; line < node.Line; line++ {
+		i := bytes.IndexByte(data[offset:], '\n')
+		if i < 0 {
+			return 0, fmt.Errorf("行号 %d 超出范围", node.Line)
+		}
+		offset += i + 1
+	}
+
+	lineText := data[offset:]
+	if i := bytes.IndexByte(lineText, '\n'); i >= 0 {
+		lineText = lineText[:i]
+	}
+	col := 1
+	for i := range string(lineText) {
+		if col == node.Column {
+			return offset + i, nil
+		}
+		col++
+	}
+	if col == node.Column {
+		return offset + len(lineText), nil
+	}
+	return 0, fmt.Errorf("列号 %d 超出范围", node.Column)
+}
+
+// scalarEnd 返回单行标量在原文中的结束偏移
+func scalarEnd(data []byte, node *yaml.Node) (int, error) {
+	start, err := nodeOffset(data, node)
+	if err != nil {
+		return 0, err
+	}
+
+	rest := data[start:]
+	if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+		rest = rest[:i]
+	}
+
+	switch {
+	case node.Style&yaml.DoubleQuotedStyle != 0:
+		for i := 1; i < len(rest); i++ {
+			switch rest[i] {
+			case '\\':
+				i++
+			case '"':
+				return start + i + 1, nil
+			}
+		}
+		return 0, fmt.Errorf("%s 的引号未闭合或跨行", node.Value)
+	case node.Style&yaml.SingleQuotedStyle != 0:
+		for i := 1; i < len(rest); i++ {
+			if rest[i] != '\'' {
+				continue
+			}
+			if i+1 < len(rest) && rest[i+1] == '\'' {
+				i++
+				continue
+			}
+			return start + i + 1, nil
+		}
+		return 0, fmt.Errorf("%s 的引号未闭合或跨行", node.Value)
+	}
+
+	// 普通标量: 到行尾注释、映射分隔符或行尾为止
+	end := len(rest)
+	if i := bytes.Index(rest, []byte(" #")); i >= 0 {
+		end = i
+	}
+	if i := bytes.Index(rest[:end], []byte(": ")); i >= 0 {
+		end = i
+	}
+	if bytes.HasSuffix(rest[:end], []byte(":")) {
+		end--
+	}
+	return start + len(bytes.TrimRight(rest[:end], " \t\r")), nil
+}
+
+// splice 用 text 替换 data[start:end]
+func splice(data []byte, start, end int, text string) []byte {
+	out := make([]byte, 0, len(data)-(end-start)+len(text))
+	out = append(out, data[:start]...)
+	out = append(out, text...)
+	return append(out, data[end:]...)
+}
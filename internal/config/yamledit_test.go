@@ -0,0 +1,166 @@
+package config
+
+import "testing"
+
+func TestSetYAMLValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		path    []string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "plain value",
+			data:  "url: http://127.0.0.1:5244\ntoken: old\nauth:\n  username: admin\n",
+			path:  []string{"token"},
+			value: "NEW",
+			want:  "url: http://127.0.0.1:5244\ntoken: NEW\nauth:\n  username: admin\n",
+		},
+		{
+			name:  "comment after value",
+			data:  "token: old   # 自动刷新\nurl: x\n",
+			path:  []string{"token"},
+			value: "NEW",
+			want:  "token: NEW   # 自动刷新\nurl: x\n",
+		},
+		{
+			name:  "double quoted",
+			data:  "token: \"o\\\"ld\" # c\n",
+			path:  []string{"token"},
+			value: "NEW",
+			want:  "token: NEW # c\n",
+		},
+		{
+			name:  "single quoted",
+			data:  "token: 'it''s' # c\n",
+			path:  []string{"token"},
+			value: "NEW",
+			want:  "token: NEW # c\n",
+		},
+		{
+			name:  "value needing quotes",
+			data:  "token: old\n",
+			path:  []string{"token"},
+			value: "a: #b",
+			want:  "token: 'a: #b'\n",
+		},
+		{
+			name:  "empty value",
+			data:  "token:\nurl: x\n",
+			path:  []string{"token"},
+			value: "NEW",
+			want:  "token: NEW\nurl: x\n",
+		},
+		{
+			name:  "empty value with comment",
+			data:  "token: # 自动获取\nurl: x\n",
+			path:  []string{"token"},
+			value: "NEW",
+			want:  "token: NEW # 自动获取\nurl: x\n",
+		},
+		{
+			name:  "tilde",
+			data:  "token: ~\n",
+			path:  []string{"token"},
+			value: "NEW",
+			want:  "token: NEW\n",
+		},
+		{
+			name:  "multibyte columns",
+			data:  "名称: 阿里云盘 # 注释\n令牌: 旧的值 # 注释\n",
+			path:  []string{"令牌"},
+			value: "新的值",
+			want:  "名称: 阿里云盘 # 注释\n令牌: 新的值 # 注释\n",
+		},
+		{
+			name:  "nested profile token",
+			data:  "token: top\nprofiles:\n  prod: # 生产\n    url: http://prod\n    token: old # c\n",
+			path:  []string{"profiles", "prod", "token"},
+			value: "NEW",
+			want:  "token: top\nprofiles:\n  prod: # 生产\n    url: http://prod\n    token: NEW # c\n",
+		},
+		{
+			name:  "insert missing profile token",
+			data:  "token: top\nprofiles:\n  prod:\n    # 生产实例\n    url: http://prod\n",
+			path:  []string{"profiles", "prod", "token"},
+			value: "NEW",
+			want:  "token: top\nprofiles:\n  prod:\n    # 生产实例\n    token: NEW\n    url: http://prod\n",
+		},
+		{
+			name:  "insert missing top level token",
+			data:  "# 配置\nurl: x\n",
+			path:  []string{"token"},
+			value: "NEW",
+			want:  "# 配置\ntoken: NEW\nurl: x\n",
+		},
+		{
+			name:  "unchanged value",
+			data:  "token: same # c\n",
+			path:  []string{"token"},
+			value: "same",
+			want:  "token: same # c\n",
+		},
+		{
+			name:    "continued plain scalar",
+			data:    "token: averylongtoken\n  continued\nurl: x\n",
+			path:    []string{"token"},
+			value:   "NEW",
+			wantErr: true,
+		},
+		{
+			name:    "double quoted across lines",
+			data:    "token: \"abc\n  def\"\n",
+			path:    []string{"token"},
+			value:   "NEW",
+			wantErr: true,
+		},
+		{
+			name:    "literal block",
+			data:    "token: |\n  abc\n",
+			path:    []string{"token"},
+			value:   "NEW",
+			wantErr: true,
+		},
+		{
+			name:    "missing profile",
+			data:    "profiles:\n  prod:\n    url: x\n",
+			path:    []string{"profiles", "test", "token"},
+			value:   "NEW",
+			wantErr: true,
+		},
+		{
+			name:    "flow mapping",
+			data:    "profiles: {prod: {token: old}}\n",
+			path:    []string{"profiles", "prod", "token"},
+			value:   "NEW",
+			wantErr: true,
+		},
+		{
+			name:    "multi-line value",
+			data:    "token: old\n",
+			path:    []string{"token"},
+			value:   "a\nb",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setYAMLValue([]byte(tt.data), tt.path, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("setYAMLValue = %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("setYAMLValue error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("setYAMLValue =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
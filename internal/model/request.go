@@ -7,6 +7,15 @@ type AuthRequest struct {
 	Password string `json:"password"`
 }
 
+// FsListRequest 列出目录请求
+type FsListRequest struct {
+	Path     string `json:"path"`
+	Password string `json:"password"`
+	Page     int    `json:"page"`
+	PerPage  int    `json:"per_page"`
+	Refresh  bool   `json:"refresh"`
+}
+
 // StorageRequest 存储挂载请求
 type StorageRequest struct {
 	MountPath        string `json:"mount_path"`
@@ -26,6 +35,45 @@ type StorageRequest struct {
 	Addition         string `json:"addition"`
 }
 
+// StorageUpdateRequest 存储更新请求, 在挂载请求的基础上带有存储 ID
+type StorageUpdateRequest struct {
+	Id int `json:"id"`
+	StorageRequest
+	Disabled bool   `json:"disabled"`
+	Status   string `json:"status"`
+}
+
+// NewStorageRequest 由已有存储构建请求, 保留全部通用字段
+func NewStorageRequest(item StorageItem) StorageRequest {
+	return StorageRequest{
+		MountPath:        item.MountPath,
+		Order:            item.Order,
+		Remark:           item.Remark,
+		CacheExpiration:  item.CacheExpiration,
+		WebProxy:         item.WebProxy,
+		WebdavPolicy:     item.WebdavPolicy,
+		DownProxyUrl:     item.DownProxyURL,
+		DisableProxySign: item.DisableProxySign,
+		OrderBy:          item.OrderBy,
+		OrderDirection:   item.OrderDirection,
+		ExtractFolder:    item.ExtractFolder,
+		DisableIndex:     item.DisableIndex,
+		EnableSign:       item.EnableSign,
+		Driver:           item.Driver,
+		Addition:         item.Addition,
+	}
+}
+
+// NewStorageUpdateRequest 由已有存储构建更新请求, 保留禁用状态并把状态重置为 work
+func NewStorageUpdateRequest(item StorageItem) *StorageUpdateRequest {
+	return &StorageUpdateRequest{
+		Id:             item.Id,
+		StorageRequest: NewStorageRequest(item),
+		Disabled:       item.Disabled,
+		Status:         "work",
+	}
+}
+
 // AliyunShareAddition 阿里云盘分享挂载附加信息
 type AliyunShareAddition struct {
 	RefreshToken   string `json:"refresh_token"`
@@ -36,6 +84,23 @@ type AliyunShareAddition struct {
 	OrderDirection string `json:"order_direction"`
 }
 
+// AliyunOpenAddition 阿里云盘 Open 挂载附加信息
+type AliyunOpenAddition struct {
+	DriveType      string `json:"drive_type"`
+	RootFolderId   string `json:"root_folder_id"`
+	RefreshToken   string `json:"refresh_token"`
+	OrderBy        string `json:"order_by"`
+	OrderDirection string `json:"order_direction"`
+	UseOnlineAPI   bool   `json:"use_online_api"`
+	AlipanType     string `json:"alipan_type"`
+	APIAddress     string `json:"api_url_address,omitempty"`
+	ClientId       string `json:"client_id"`
+	ClientSecret   string `json:"client_secret"`
+	RemoveWay      string `json:"remove_way"`
+	RapidUpload    bool   `json:"rapid_upload"`
+	InternalUpload bool   `json:"internal_upload"`
+}
+
 // PikPakShareAddition PikPak Share 挂载附加信息
 type PikPakShareAddition struct {
 	RootFolderId          string `json:"root_folder_id"`
@@ -46,6 +111,94 @@ type PikPakShareAddition struct {
 	UseTranscodingAddress bool   `json:"use_transcoding_address"`
 }
 
+// PikPakAddition PikPak 账户挂载附加信息
+type PikPakAddition struct {
+	RootFolderId     string `json:"root_folder_id"`
+	Username         string `json:"username"`
+	Password         string `json:"password"`
+	Platform         string `json:"platform"`
+	RefreshToken     string `json:"refresh_token"`
+	CaptchaToken     string `json:"captcha_token"`
+	DeviceId         string `json:"device_id"`
+	DisableMediaLink bool   `json:"disable_media_link"`
+}
+
+// WebDavAddition WebDAV 挂载附加信息
+type WebDavAddition struct {
+	Vendor                string `json:"vendor"`
+	Address               string `json:"address"`
+	Username              string `json:"username"`
+	Password              string `json:"password"`
+	RootFolderPath        string `json:"root_folder_path"`
+	TlsInsecureSkipVerify bool   `json:"tls_insecure_skip_verify"`
+}
+
+// S3Addition S3 挂载附加信息
+type S3Addition struct {
+	RootFolderPath    string `json:"root_folder_path"`
+	Bucket            string `json:"bucket"`
+	Endpoint          string `json:"endpoint"`
+	Region            string `json:"region"`
+	AccessKeyID       string `json:"access_key_id"`
+	SecretAccessKey   string `json:"secret_access_key"`
+	SessionToken      string `json:"session_token"`
+	CustomHost        string `json:"custom_host"`
+	SignURLExpire     int    `json:"sign_url_expire"`
+	Placeholder       string `json:"placeholder"`
+	ForcePathStyle    bool   `json:"force_path_style"`
+	ListObjectVersion string `json:"list_object_version"`
+	RemoveBucket      bool   `json:"remove_bucket"`
+}
+
+// SFTPAddition SFTP 挂载附加信息
+type SFTPAddition struct {
+	Address            string `json:"address"`
+	Username           string `json:"username"`
+	PrivateKey         string `json:"private_key"`
+	Password           string `json:"password"`
+	Passphrase         string `json:"passphrase"`
+	RootFolderPath     string `json:"root_folder_path"`
+	IgnoreSymlinkError bool   `json:"ignore_symlink_error"`
+}
+
+// FTPAddition FTP 挂载附加信息
+type FTPAddition struct {
+	Address        string `json:"address"`
+	Encoding       string `json:"encoding"`
+	Username       string `json:"username"`
+	Password       string `json:"password"`
+	RootFolderPath string `json:"root_folder_path"`
+}
+
+// SMBAddition SMB 挂载附加信息
+type SMBAddition struct {
+	RootFolderPath string `json:"root_folder_path"`
+	Address        string `json:"address"`
+	Username       string `json:"username"`
+	Password       string `json:"password"`
+	ShareName      string `json:"share_name"`
+}
+
+// LocalAddition 本地存储挂载附加信息
+type LocalAddition struct {
+	RootFolderPath   string `json:"root_folder_path"`
+	DirectorySize    bool   `json:"directory_size"`
+	Thumbnail        bool   `json:"thumbnail"`
+	ThumbCacheFolder string `json:"thumb_cache_folder"`
+	ThumbConcurrency string `json:"thumb_concurrency"`
+	VideoThumbPos    string `json:"video_thumb_pos"`
+	ShowHidden       bool   `json:"show_hidden"`
+	MkdirPerm        string `json:"mkdir_perm"`
+	RecycleBinPath   string `json:"recycle_bin_path"`
+}
+
+// AliasAddition 别名存储挂载附加信息
+type AliasAddition struct {
+	Paths           string `json:"paths"` // 每行一个路径
+	ProtectSameName bool   `json:"protect_same_name"`
+	Writable        bool   `json:"writable"`
+}
+
 // OneDriveAppAddition OneDrive APP 挂载附加信息
 type OneDriveAppAddition struct {
 	RootFolderPath string `json:"root_folder_path"`
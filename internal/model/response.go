@@ -44,3 +44,18 @@ type StorageItem struct {
 	DownProxyURL     string    `json:"down_proxy_url"`
 	DisableProxySign bool      `json:"disable_proxy_sign"`
 }
+
+// FsListResponse 列出目录响应
+type FsListResponse struct {
+	Content []FsObject `json:"content"`
+	Total   int        `json:"total"`
+}
+
+// FsObject 目录中的文件或文件夹
+type FsObject struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	IsDir    bool      `json:"is_dir"`
+	Modified time.Time `json:"modified"`
+}
@@ -0,0 +1,176 @@
+// Package provider 提供别名存储支持
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
+
+// Alias 别名存储提供商, 把已有的多个挂载路径合并为一个目录
+type Alias struct{}
+
+// NewAlias 创建别名存储提供商
+func NewAlias() *Alias {
+	return &Alias{}
+}
+
+// Name 返回提供商名称
+func (a *Alias) Name() string {
+	return "别名存储"
+}
+
+// Driver 返回 OpenList 驱动名称
+func (a *Alias) Driver() string {
+	return "Alias"
+}
+
+// aliasPath 别名中的一个路径
+type aliasPath struct {
+	name string // 显示名称, 为空时使用路径的最后一级
+	path string
+}
+
+// String 返回 OpenList paths 中的一行
+func (p aliasPath) String() string {
+	if p.name == "" {
+		return p.path
+	}
+	return p.name + ":" + p.path
+}
+
+// BuildRequest 构建存储挂载请求
+//
+// value 为路径列表 (alias.yaml 中的列表, 加载时编码为 JSON) 或单个路径, 见 parseAliasPaths
+func (a *Alias) BuildRequest(mountPath string, value string) (*model.StorageRequest, error) {
+	paths, err := parseAliasPaths(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAliasPaths(mountPath, paths); err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0, len(paths))
+	for _, p := range paths {
+		lines = append(lines, p.String())
+	}
+	addition := model.AliasAddition{
+		Paths:           strings.Join(lines, "\n"),
+		ProtectSameName: true,
+		Writable:        false,
+	}
+
+	additionJSON, err := json.Marshal(addition)
+	if err != nil {
+		return nil, fmt.Errorf("序列化附加信息失败: %w", err)
+	}
+
+	return &model.StorageRequest{
+		MountPath:       mountPath,
+		Order:           0,
+		Remark:          "",
+		CacheExpiration: 30,
+		WebProxy:        false,
+		WebdavPolicy:    "native_proxy",
+		DownProxyUrl:    "",
+		OrderBy:         "",
+		OrderDirection:  "",
+		ExtractFolder:   "",
+		EnableSign:      false,
+		Driver:          a.Driver(),
+		Addition:        string(additionJSON),
+	}, nil
+}
+
+// Validate 静态检查路径格式, 不检查路径是否存在
+func (a *Alias) Validate(value string) error {
+	paths, err := parseAliasPaths(value)
+	if err != nil {
+		return err
+	}
+	return checkAliasPaths("", paths)
+}
+
+// CheckMounts 检查每个路径是否为已有存储的挂载路径或其下的目录
+func (a *Alias) CheckMounts(value string, mounts []string) error {
+	paths, err := parseAliasPaths(value)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, p := range paths {
+		found := false
+		for _, mount := range mounts {
+			if p.path == mount || mount == "/" || strings.HasPrefix(p.path, mount+"/") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("路径 %s 不在任何已有存储下", p.path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parseAliasPaths 解析路径列表, 每一项为 /路径 或 名称:/路径
+//
+//	["/阿里云盘/电影", "PikPak:/PikPak/电影"]
+//	/阿里云盘/电影
+func parseAliasPaths(value string) ([]aliasPath, error) {
+	v := strings.TrimSpace(value)
+	var items []string
+	if strings.HasPrefix(v, "[") {
+		if err := json.Unmarshal([]byte(v), &items); err != nil {
+			return nil, fmt.Errorf("无效的路径列表: %w", err)
+		}
+	} else {
+		items = []string{v}
+	}
+
+	var paths []aliasPath
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		var p aliasPath
+		if name, rest, ok := strings.Cut(item, ":"); ok && !strings.HasPrefix(item, "/") {
+			p = aliasPath{name: strings.TrimSpace(name), path: strings.TrimSpace(rest)}
+		} else {
+			p = aliasPath{path: item}
+		}
+		if p.path != "/" {
+			p.path = strings.TrimSuffix(p.path, "/")
+		}
+		paths = append(paths, p)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("别名至少需要一个路径")
+	}
+	return paths, nil
+}
+
+// checkAliasPaths 检查路径格式和重复, mountPath 不为空时检查路径不在别名自身之下
+func checkAliasPaths(mountPath string, paths []aliasPath) error {
+	var errs []error
+	seen := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		switch {
+		case !strings.HasPrefix(p.path, "/") || path.Clean(p.path) != p.path:
+			errs = append(errs, fmt.Errorf("路径 %q 应以 / 开头且不包含多余的 / 或 ..", p.path))
+		case seen[p.String()]:
+			errs = append(errs, fmt.Errorf("路径 %s 重复", p.path))
+		case mountPath != "" && (p.path == mountPath || strings.HasPrefix(p.path, mountPath+"/")):
+			errs = append(errs, fmt.Errorf("路径 %s 位于别名 %s 自身之下", p.path, mountPath))
+		}
+		seen[p.String()] = true
+	}
+	return errors.Join(errs...)
+}
@@ -1,12 +1,16 @@
-// Package provider 提供阿里云盘存储支持
+// Package provider 提供阿里云盘分享与 Open 存储支持
 package provider
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
+	"regexp"
+	"slices"
 	"strings"
 
+	"github.com/yzbtdiy/openlist_batch/internal/config"
 	"github.com/yzbtdiy/openlist_batch/internal/model"
 )
 
@@ -32,28 +36,16 @@ func (a *AliyunShare) Driver() string {
 
 // BuildRequest 构建存储挂载请求
 func (a *AliyunShare) BuildRequest(mountPath string, shareURL string) (*model.StorageRequest, error) {
-	parsed, err := url.Parse(shareURL)
+	link, err := parseAliyunShareURL(shareURL)
 	if err != nil {
-		return nil, fmt.Errorf("解析分享链接失败: %w", err)
-	}
-
-	// 提取分享密码
-	sharePwd := parsed.Query().Get("pwd")
-
-	// 解析路径: /s/shareId/folder/folderId
-	pathParts := strings.Split(parsed.Path, "/")
-	if len(pathParts) < 5 {
-		return nil, fmt.Errorf("无效的阿里云盘分享链接格式")
+		return nil, err
 	}
 
-	shareID := pathParts[2]
-	folderID := pathParts[4]
-
 	addition := model.AliyunShareAddition{
 		RefreshToken:   a.RefreshToken,
-		ShareId:        shareID,
-		SharePwd:       sharePwd,
-		RootFolderId:   folderID,
+		ShareId:        link.shareID,
+		SharePwd:       link.pwd,
+		RootFolderId:   link.folderID,
 		OrderBy:        "",
 		OrderDirection: "",
 	}
@@ -80,32 +72,315 @@ func (a *AliyunShare) BuildRequest(mountPath string, shareURL string) (*model.St
 	}, nil
 }
 
+// aliyunHosts 阿里云盘分享链接的域名, 包括移动端分享页
+var aliyunHosts = []string{
+	"aliyundrive.com", "www.aliyundrive.com", "m.aliyundrive.com",
+	"alipan.com", "www.alipan.com", "m.alipan.com",
+}
+
+// aliyunRootFolderID 分享根目录的文件夹 ID
+const aliyunRootFolderID = "root"
+
+var (
+	aliyunShareIDPattern  = regexp.MustCompile(`^[0-9A-Za-z]{11}$`)
+	aliyunFolderIDPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+	// shareURLPattern 从分享文本中找出链接, 遇到空白、引号或中文标点结束
+	shareURLPattern = regexp.MustCompile(`https?://[^\s"'<>，。；、！？（）「」【】]+`)
+	// sharePwdTextPattern 分享文本中的提取码, 如 "提取码: ab12"
+	sharePwdTextPattern = regexp.MustCompile(`(?i)(?:提取码|密码|pwd|code)\s*[:：=]?\s*([0-9A-Za-z]{4})\b`)
+)
+
+// aliyunShareLink 从分享链接中解析出的信息
+type aliyunShareLink struct {
+	url      *url.URL
+	shareID  string
+	folderID string
+	pwd      string
+}
+
+// parseAliyunShareURL 解析阿里云盘分享链接
+//
+// 支持的形式:
+//
+//	https://www.alipan.com/s/shareId                          分享根目录
+//	https://www.alipan.com/s/shareId/folder/folderId?pwd=xxxx 子文件夹
+//	https://www.aliyundrive.com/s/shareId/folder/folderId#pwd=xxxx
+//	移动端分享页、带末尾斜杠或 # 片段的链接
+//	App 复制的分享文本, 如 "「电影」https://www.alipan.com/s/shareId 提取码: xxxx"
+func parseAliyunShareURL(value string) (*aliyunShareLink, error) {
+	value = strings.TrimSpace(value)
+	raw := shareURLPattern.FindString(value)
+	if raw == "" {
+		return nil, fmt.Errorf("没有找到阿里云盘分享链接")
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("解析分享链接失败: %w", err)
+	}
+
+	// 解析路径: /s/shareId 或 /s/shareId/folder/folderId, 移动端页面可能带有前缀
+	var segments []string
+	for _, seg := range strings.Split(parsed.Path, "/") {
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	i := slices.Index(segments, "s")
+	if i < 0 || i+1 >= len(segments) {
+		return nil, fmt.Errorf("无效的阿里云盘分享链接格式, 应为 /s/分享ID[/folder/文件夹ID]")
+	}
+
+	link := &aliyunShareLink{url: parsed, shareID: segments[i+1], folderID: aliyunRootFolderID}
+	switch rest := segments[i+2:]; {
+	case len(rest) == 0:
+	case len(rest) == 2 && rest[0] == "folder":
+		link.folderID = rest[1]
+	default:
+		return nil, fmt.Errorf("无效的阿里云盘分享链接格式, 应为 /s/分享ID[/folder/文件夹ID]")
+	}
+
+	// 提取码依次从查询参数、# 片段和分享文本中查找
+	link.pwd = parsed.Query().Get("pwd")
+	if link.pwd == "" {
+		if fragment, err := url.ParseQuery(parsed.Fragment); err == nil {
+			link.pwd = fragment.Get("pwd")
+		}
+	}
+	if link.pwd == "" {
+		if m := sharePwdTextPattern.FindStringSubmatch(strings.Replace(value, raw, " ", 1)); m != nil {
+			link.pwd = m[1]
+		}
+	}
+	return link, nil
+}
+
+// Validate 静态检查分享链接: 域名、分享 ID 与文件夹 ID 格式、提取码长度
+func (a *AliyunShare) Validate(shareURL string) error {
+	link, err := parseAliyunShareURL(shareURL)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if err := checkShareHost(link.url, aliyunHosts); err != nil {
+		errs = append(errs, err)
+	}
+	if !aliyunShareIDPattern.MatchString(link.shareID) {
+		errs = append(errs, fmt.Errorf("分享 ID %q 格式不正确, 应为 11 位字母或数字", link.shareID))
+	}
+	if link.folderID != aliyunRootFolderID && !aliyunFolderIDPattern.MatchString(link.folderID) {
+		errs = append(errs, fmt.Errorf("文件夹 ID %q 格式不正确, 应为 40 位十六进制字符", link.folderID))
+	}
+	if err := checkSharePwd(link.pwd); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
 // BuildUpdateRequest 构建更新请求 (更新 RefreshToken)
-func (a *AliyunShare) BuildUpdateRequest(item model.StorageItem, newToken string) (*model.StorageRequest, error) {
-	var oldAddition model.AliyunShareAddition
-	if err := json.Unmarshal([]byte(item.Addition), &oldAddition); err != nil {
-		return nil, fmt.Errorf("解析原有附加信息失败: %w", err)
+func (a *AliyunShare) BuildUpdateRequest(item model.StorageItem) (*model.StorageUpdateRequest, bool, error) {
+	return buildCredentialUpdate(item, map[string]any{"refresh_token": a.RefreshToken})
+}
+
+// AliyunOpen 阿里云盘 Open 提供商, 挂载自己网盘中的文件夹
+type AliyunOpen struct {
+	cfg config.AliyunOpen
+}
+
+// NewAliyunOpen 创建阿里云盘 Open 提供商
+func NewAliyunOpen(cfg config.AliyunOpen) *AliyunOpen {
+	return &AliyunOpen{cfg: cfg}
+}
+
+// Name 返回提供商名称
+func (a *AliyunOpen) Name() string {
+	return "阿里云盘 Open"
+}
+
+// Driver 返回 OpenList 驱动名称
+func (a *AliyunOpen) Driver() string {
+	return "AliyundriveOpen"
+}
+
+// BuildRequest 构建存储挂载请求
+//
+// value 为 "[网盘类型:]文件夹", 见 parseAliyunOpenFolder; 以路径指定的文件夹需要先用
+// FolderPath 和 WithFolderID 换成文件夹 ID
+func (a *AliyunOpen) BuildRequest(mountPath string, value string) (*model.StorageRequest, error) {
+	folder, err := parseAliyunOpenFolder(value)
+	if err != nil {
+		return nil, err
 	}
+	if folder.dir != "" {
+		return nil, fmt.Errorf("文件夹路径 %s 需要连接 OpenList 解析为文件夹 ID", folder.dir)
+	}
+	return a.buildRequest(mountPath, folder.driveType, folder.folderID)
+}
+
+// FolderPath 返回条目中以路径指定的文件夹, 以及挂载同一网盘根目录的请求
+func (a *AliyunOpen) FolderPath(value string) (*model.StorageRequest, string, error) {
+	folder, err := parseAliyunOpenFolder(value)
+	if err != nil || folder.dir == "" {
+		return nil, "", err
+	}
+	root, err := a.buildRequest("", folder.driveType, aliyunRootFolderID)
+	if err != nil {
+		return nil, "", err
+	}
+	return root, folder.dir, nil
+}
 
-	oldAddition.RefreshToken = newToken
-	newAdditionJSON, err := json.Marshal(oldAddition)
+// WithFolderID 返回把路径换成文件夹 ID 后的条目, 保留网盘类型前缀
+func (a *AliyunOpen) WithFolderID(value, folderID string) (string, error) {
+	folder, err := parseAliyunOpenFolder(value)
+	if err != nil {
+		return "", err
+	}
+	if folder.driveType == "" {
+		return folderID, nil
+	}
+	return folder.driveType + ":" + folderID, nil
+}
+
+// buildRequest 按网盘类型和文件夹 ID 构建存储挂载请求, 网盘类型为空时使用配置的默认网盘
+func (a *AliyunOpen) buildRequest(mountPath, driveType, folderID string) (*model.StorageRequest, error) {
+	if driveType == "" {
+		driveType = a.cfg.DefaultDrive()
+	}
+
+	addition := model.AliyunOpenAddition{
+		DriveType:    driveType,
+		RootFolderId: folderID,
+		RefreshToken: a.cfg.RefreshToken,
+		UseOnlineAPI: a.cfg.UseOnlineAPI(),
+		AlipanType:   a.cfg.PanType(),
+		APIAddress:   a.cfg.APIURL,
+		ClientId:     a.cfg.ClientID,
+		ClientSecret: a.cfg.ClientSecret,
+		RemoveWay:    a.cfg.RemovePolicy(),
+	}
+
+	additionJSON, err := json.Marshal(addition)
 	if err != nil {
 		return nil, fmt.Errorf("序列化附加信息失败: %w", err)
 	}
 
 	return &model.StorageRequest{
-		MountPath:       item.MountPath,
-		Order:           item.Order,
-		Remark:          item.Remark,
-		CacheExpiration: item.CacheExpiration,
-		WebProxy:        item.WebProxy,
-		WebdavPolicy:    item.WebdavPolicy,
-		DownProxyUrl:    item.DownProxyURL,
-		OrderBy:         item.OrderBy,
-		OrderDirection:  item.OrderDirection,
-		ExtractFolder:   item.ExtractFolder,
+		MountPath:       mountPath,
+		Order:           0,
+		Remark:          "",
+		CacheExpiration: 30,
+		WebProxy:        false,
+		WebdavPolicy:    "302_redirect",
+		DownProxyUrl:    "",
+		OrderBy:         "",
+		OrderDirection:  "",
+		ExtractFolder:   "",
 		EnableSign:      false,
-		Driver:          item.Driver,
-		Addition:        string(newAdditionJSON),
+		Driver:          a.Driver(),
+		Addition:        string(additionJSON),
 	}, nil
 }
+
+// BuildUpdateRequest 构建更新请求 (更新 refresh_token 与刷新 token 的方式)
+func (a *AliyunOpen) BuildUpdateRequest(item model.StorageItem) (*model.StorageUpdateRequest, bool, error) {
+	credentials := map[string]any{
+		"refresh_token":  a.cfg.RefreshToken,
+		"use_online_api": a.cfg.UseOnlineAPI(),
+		"alipan_type":    a.cfg.PanType(),
+		"client_id":      a.cfg.ClientID,
+		"client_secret":  a.cfg.ClientSecret,
+	}
+	if a.cfg.APIURL != "" {
+		credentials["api_url_address"] = a.cfg.APIURL
+	}
+	return buildCredentialUpdate(item, credentials)
+}
+
+// Validate 静态检查挂载条目: 网盘类型与文件夹 ID 格式; 不检查文件夹路径是否存在
+func (a *AliyunOpen) Validate(value string) error {
+	folder, err := parseAliyunOpenFolder(value)
+	if err != nil {
+		return err
+	}
+	if folder.dir == "" && folder.folderID != aliyunRootFolderID && !aliyunFolderIDPattern.MatchString(folder.folderID) {
+		return fmt.Errorf("文件夹 ID %q 格式不正确", folder.folderID)
+	}
+	return nil
+}
+
+// aliyunOpenFolder 挂载条目中的文件夹
+type aliyunOpenFolder struct {
+	driveType string // 为空时使用配置的默认网盘
+	folderID  string
+	dir       string // 以路径指定的子文件夹, 不为空时 folderID 为空
+}
+
+// aliyunDriveTypes 可以写在条目前缀和网页地址中的网盘类型
+var aliyunDriveTypes = []string{config.AliyunDriveResource, config.AliyunDriveBackup, config.AliyunDriveDefault}
+
+// parseAliyunOpenFolder 解析挂载条目 "[网盘类型:]文件夹"
+//
+// 文件夹为 / 或 root (根目录)、/ 开头的子文件夹路径、40 位文件夹 ID, 或网页版中文件夹的地址
+// (https://www.alipan.com/drive/file/resource/文件夹ID, 地址中的网盘类型同时生效);
+// 网盘类型为 resource、backup 或 default
+func parseAliyunOpenFolder(value string) (aliyunOpenFolder, error) {
+	var folder aliyunOpenFolder
+	value = strings.TrimSpace(value)
+	if head, rest, ok := strings.Cut(value, ":"); ok && head != "http" && head != "https" {
+		if !slices.Contains(aliyunDriveTypes, head) {
+			return folder, fmt.Errorf("未知的网盘类型 %s, 可选值: %s", head, strings.Join(aliyunDriveTypes, ", "))
+		}
+		folder.driveType, value = head, strings.TrimSpace(rest)
+	}
+
+	if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+		parsed, err := url.Parse(value)
+		if err != nil {
+			return folder, fmt.Errorf("解析文件夹地址失败: %w", err)
+		}
+		if err := checkShareHost(parsed, aliyunHosts); err != nil {
+			return folder, err
+		}
+		parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+		if len(parts) < 2 || parts[0] != "drive" {
+			return folder, fmt.Errorf("无效的阿里云盘文件夹地址, 应为 /drive/file/resource/文件夹ID")
+		}
+		for _, part := range parts[1 : len(parts)-1] {
+			if slices.Contains(aliyunDriveTypes, part) && folder.driveType == "" {
+				folder.driveType = part
+			}
+		}
+		switch last := parts[len(parts)-1]; {
+		case slices.Contains(aliyunDriveTypes, last):
+			if folder.driveType == "" {
+				folder.driveType = last
+			}
+			folder.folderID = aliyunRootFolderID
+		case last == "file" || last == "all":
+			folder.folderID = aliyunRootFolderID
+		default:
+			folder.folderID = last
+		}
+		return folder, nil
+	}
+
+	dir, err := parseFolderPath(value)
+	if err != nil {
+		return folder, err
+	}
+	switch {
+	case dir == "/" || value == "":
+		folder.folderID = aliyunRootFolderID
+	case dir != "":
+		folder.dir = dir
+	default:
+		folder.folderID = strings.TrimSuffix(value, "/")
+		if strings.Contains(folder.folderID, "/") {
+			return folder, fmt.Errorf("文件夹 %q 应为文件夹 ID 或以 / 开头的路径", value)
+		}
+	}
+	return folder, nil
+}
@@ -0,0 +1,285 @@
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
+
+const (
+	testAliyunShareID  = "MmMR3zaoXLf"
+	testAliyunFolderID = "61d259418d27bae8656f47aca23ee03b40275432"
+)
+
+func TestParseAliyunShareURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		shareID  string
+		folderID string
+		pwd      string
+		wantErr  bool
+	}{
+		{
+			name:     "share root",
+			value:    "https://www.alipan.com/s/" + testAliyunShareID,
+			shareID:  testAliyunShareID,
+			folderID: "root",
+		},
+		{
+			name:     "folder on alipan.com",
+			value:    "https://www.alipan.com/s/" + testAliyunShareID + "/folder/" + testAliyunFolderID,
+			shareID:  testAliyunShareID,
+			folderID: testAliyunFolderID,
+		},
+		{
+			name:     "folder on aliyundrive.com",
+			value:    "https://www.aliyundrive.com/s/" + testAliyunShareID + "/folder/" + testAliyunFolderID,
+			shareID:  testAliyunShareID,
+			folderID: testAliyunFolderID,
+		},
+		{
+			name:     "bare host",
+			value:    "https://alipan.com/s/" + testAliyunShareID,
+			shareID:  testAliyunShareID,
+			folderID: "root",
+		},
+		{
+			name:     "trailing slash on share root",
+			value:    "https://www.alipan.com/s/" + testAliyunShareID + "/",
+			shareID:  testAliyunShareID,
+			folderID: "root",
+		},
+		{
+			name:     "trailing slash on folder",
+			value:    "https://www.alipan.com/s/" + testAliyunShareID + "/folder/" + testAliyunFolderID + "/",
+			shareID:  testAliyunShareID,
+			folderID: testAliyunFolderID,
+		},
+		{
+			name:     "empty fragment",
+			value:    "https://www.alipan.com/s/" + testAliyunShareID + "#",
+			shareID:  testAliyunShareID,
+			folderID: "root",
+		},
+		{
+			name:     "mobile share page",
+			value:    "https://m.alipan.com/s/" + testAliyunShareID + "/folder/" + testAliyunFolderID,
+			shareID:  testAliyunShareID,
+			folderID: testAliyunFolderID,
+		},
+		{
+			name:     "pwd in query",
+			value:    "https://www.alipan.com/s/" + testAliyunShareID + "?pwd=ab12",
+			shareID:  testAliyunShareID,
+			folderID: "root",
+			pwd:      "ab12",
+		},
+		{
+			name:     "pwd in fragment",
+			value:    "https://www.aliyundrive.com/s/" + testAliyunShareID + "/folder/" + testAliyunFolderID + "#pwd=ab12",
+			shareID:  testAliyunShareID,
+			folderID: testAliyunFolderID,
+			pwd:      "ab12",
+		},
+		{
+			name:     "pwd in share text",
+			value:    "「电影」https://www.alipan.com/s/" + testAliyunShareID + " 提取码: ab12 点击链接保存",
+			shareID:  testAliyunShareID,
+			folderID: "root",
+			pwd:      "ab12",
+		},
+		{
+			name:     "share text with full-width colon",
+			value:    "我用阿里云盘分享了「电影」，你可以不限速下载🚀\nhttps://www.alipan.com/s/" + testAliyunShareID + "\n提取码：ab12",
+			shareID:  testAliyunShareID,
+			folderID: "root",
+			pwd:      "ab12",
+		},
+		{
+			name:    "no link",
+			value:   "提取码: ab12",
+			wantErr: true,
+		},
+		{
+			name:    "missing share id",
+			value:   "https://www.alipan.com/s/",
+			wantErr: true,
+		},
+		{
+			name:    "not a share path",
+			value:   "https://www.alipan.com/drive/file/" + testAliyunFolderID,
+			wantErr: true,
+		},
+		{
+			name:    "folder without id",
+			value:   "https://www.alipan.com/s/" + testAliyunShareID + "/folder",
+			wantErr: true,
+		},
+		{
+			name:    "unknown segment after share id",
+			value:   "https://www.alipan.com/s/" + testAliyunShareID + "/file/" + testAliyunFolderID,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link, err := parseAliyunShareURL(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseAliyunShareURL(%q) = %+v, want error", tt.value, link)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAliyunShareURL(%q) error: %v", tt.value, err)
+			}
+			if link.shareID != tt.shareID || link.folderID != tt.folderID || link.pwd != tt.pwd {
+				t.Errorf("parseAliyunShareURL(%q) = share %q folder %q pwd %q, want %q %q %q",
+					tt.value, link.shareID, link.folderID, link.pwd, tt.shareID, tt.folderID, tt.pwd)
+			}
+		})
+	}
+}
+
+func TestAliyunShareBuildRequest(t *testing.T) {
+	p := NewAliyunShare("token")
+	req, err := p.BuildRequest("/电影/合集", "https://www.alipan.com/s/"+testAliyunShareID+"/#pwd=ab12")
+	if err != nil {
+		t.Fatalf("BuildRequest error: %v", err)
+	}
+	if req.MountPath != "/电影/合集" || req.Driver != "AliyundriveShare" {
+		t.Errorf("BuildRequest = mount %q driver %q", req.MountPath, req.Driver)
+	}
+
+	var a model.AliyunShareAddition
+	if err := json.Unmarshal([]byte(req.Addition), &a); err != nil {
+		t.Fatalf("addition: %v", err)
+	}
+	want := model.AliyunShareAddition{RefreshToken: "token", ShareId: testAliyunShareID, SharePwd: "ab12", RootFolderId: "root"}
+	if a != want {
+		t.Errorf("addition = %+v, want %+v", a, want)
+	}
+
+	if _, err := p.BuildRequest("/电影/合集", "https://www.alipan.com/x/"+testAliyunShareID); err == nil {
+		t.Error("BuildRequest accepted a link without /s/")
+	}
+}
+
+func TestAliyunShareValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"share root", "https://www.alipan.com/s/" + testAliyunShareID, false},
+		{"folder with pwd", "https://www.aliyundrive.com/s/" + testAliyunShareID + "/folder/" + testAliyunFolderID + "?pwd=ab12", false},
+		{"mobile host", "https://m.aliyundrive.com/s/" + testAliyunShareID, false},
+		{"other host", "https://www.example.com/s/" + testAliyunShareID, true},
+		{"ftp scheme", "ftp://www.alipan.com/s/" + testAliyunShareID, true},
+		{"short share id", "https://www.alipan.com/s/abc", true},
+		{"share id with symbol", "https://www.alipan.com/s/MmMR3zaoX-f", true},
+		{"bad folder id", "https://www.alipan.com/s/" + testAliyunShareID + "/folder/xyz", true},
+		{"uppercase folder id", "https://www.alipan.com/s/" + testAliyunShareID + "/folder/61D259418D27BAE8656F47ACA23EE03B40275432", true},
+		{"long pwd", "https://www.alipan.com/s/" + testAliyunShareID + "?pwd=abcde", true},
+		{"pwd with symbol", "https://www.alipan.com/s/" + testAliyunShareID + "#pwd=ab-1", true},
+	}
+
+	p := NewAliyunShare("token")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Validate(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseAliyunOpenFolder(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    aliyunOpenFolder
+		wantErr bool
+	}{
+		{name: "root", value: "/", want: aliyunOpenFolder{folderID: "root"}},
+		{name: "empty", value: "", want: aliyunOpenFolder{folderID: "root"}},
+		{name: "root id", value: "root", want: aliyunOpenFolder{folderID: "root"}},
+		{name: "backup root", value: "backup:/", want: aliyunOpenFolder{driveType: "backup", folderID: "root"}},
+		{name: "folder id", value: testAliyunFolderID, want: aliyunOpenFolder{folderID: testAliyunFolderID}},
+		{name: "resource folder id", value: "resource:" + testAliyunFolderID, want: aliyunOpenFolder{driveType: "resource", folderID: testAliyunFolderID}},
+		{name: "path", value: "/资料/文档", want: aliyunOpenFolder{dir: "/资料/文档"}},
+		{name: "single level path", value: "/资料/", want: aliyunOpenFolder{dir: "/资料"}},
+		{name: "backup path", value: "backup:/资料", want: aliyunOpenFolder{driveType: "backup", dir: "/资料"}},
+		{name: "drive url", value: "https://www.alipan.com/drive/file/backup/" + testAliyunFolderID, want: aliyunOpenFolder{driveType: "backup", folderID: testAliyunFolderID}},
+		{name: "drive url root", value: "https://www.alipan.com/drive/file/resource", want: aliyunOpenFolder{driveType: "resource", folderID: "root"}},
+		{name: "unknown drive type", value: "photo:/", wantErr: true},
+		{name: "path with dot dot", value: "/资料/../文档", wantErr: true},
+		{name: "relative path", value: "资料/文档", wantErr: true},
+		{name: "other host", value: "https://example.com/drive/file/resource/" + testAliyunFolderID, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAliyunOpenFolder(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseAliyunOpenFolder(%q) = %+v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAliyunOpenFolder(%q) error: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseAliyunOpenFolder(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAliyunOpenFolderPath(t *testing.T) {
+	p := NewAliyunOpen(config.AliyunOpen{RefreshToken: "token", DriveType: "resource"})
+
+	root, dir, err := p.FolderPath("backup:/资料/文档")
+	if err != nil {
+		t.Fatalf("FolderPath error: %v", err)
+	}
+	if dir != "/资料/文档" {
+		t.Errorf("FolderPath dir = %q, want /资料/文档", dir)
+	}
+	var a model.AliyunOpenAddition
+	if err := json.Unmarshal([]byte(root.Addition), &a); err != nil {
+		t.Fatalf("addition: %v", err)
+	}
+	if a.RootFolderId != "root" || a.DriveType != "backup" {
+		t.Errorf("root addition = folder %q drive %q, want root of backup", a.RootFolderId, a.DriveType)
+	}
+
+	if _, dir, err := p.FolderPath(testAliyunFolderID); err != nil || dir != "" {
+		t.Errorf("FolderPath(id) = dir %q err %v, want no path", dir, err)
+	}
+	if _, err := p.BuildRequest("/阿里云盘/资料", "/资料"); err == nil {
+		t.Error("BuildRequest accepted an unresolved path")
+	}
+	if err := p.Validate("/资料/文档"); err != nil {
+		t.Errorf("Validate(path) error: %v", err)
+	}
+
+	for value, want := range map[string]string{
+		"/资料":        testAliyunFolderID,
+		"backup:/资料": "backup:" + testAliyunFolderID,
+	} {
+		got, err := p.WithFolderID(value, testAliyunFolderID)
+		if err != nil || got != want {
+			t.Errorf("WithFolderID(%q) = %q, %v, want %q", value, got, err, want)
+		}
+		if err := p.Validate(got); err != nil {
+			t.Errorf("Validate(%q) error: %v", got, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package provider 提供本地存储支持
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
+
+// Local 本地存储提供商
+type Local struct{}
+
+// NewLocal 创建本地存储提供商
+func NewLocal() *Local {
+	return &Local{}
+}
+
+// Name 返回提供商名称
+func (l *Local) Name() string {
+	return "本地存储"
+}
+
+// Driver 返回 OpenList 驱动名称
+func (l *Local) Driver() string {
+	return "Local"
+}
+
+// BuildRequest 构建存储挂载请求
+//
+// value 为 OpenList 所在主机上的绝对路径, 如 /mnt/media/电影 或 D:\Media
+func (l *Local) BuildRequest(mountPath string, value string) (*model.StorageRequest, error) {
+	dir, err := parseLocalDir(value)
+	if err != nil {
+		return nil, err
+	}
+
+	addition := model.LocalAddition{
+		RootFolderPath:   dir,
+		DirectorySize:    false,
+		Thumbnail:        false,
+		ThumbCacheFolder: "",
+		ThumbConcurrency: "16",
+		VideoThumbPos:    "20%",
+		ShowHidden:       true,
+		MkdirPerm:        "777",
+		RecycleBinPath:   "delete permanently",
+	}
+
+	additionJSON, err := json.Marshal(addition)
+	if err != nil {
+		return nil, fmt.Errorf("序列化附加信息失败: %w", err)
+	}
+
+	return &model.StorageRequest{
+		MountPath:       mountPath,
+		Order:           0,
+		Remark:          "",
+		CacheExpiration: 30,
+		WebProxy:        false,
+		WebdavPolicy:    "native_proxy",
+		DownProxyUrl:    "",
+		OrderBy:         "",
+		OrderDirection:  "",
+		ExtractFolder:   "",
+		EnableSign:      false,
+		Driver:          l.Driver(),
+		Addition:        string(additionJSON),
+	}, nil
+}
+
+// Validate 静态检查目录是否为绝对路径
+func (l *Local) Validate(value string) error {
+	_, err := parseLocalDir(value)
+	return err
+}
+
+// parseLocalDir 检查并规范化目录: 应为 / 开头或带盘符的绝对路径, 不包含 ..;
+// 去掉末尾的分隔符
+func parseLocalDir(value string) (string, error) {
+	dir := strings.TrimSpace(value)
+	windows := len(dir) >= 3 && dir[1] == ':' && (dir[2] == '\\' || dir[2] == '/') &&
+		('a' <= dir[0]|0x20 && dir[0]|0x20 <= 'z')
+	if !strings.HasPrefix(dir, "/") && !windows {
+		return "", fmt.Errorf("目录 %q 应为绝对路径, 如 /mnt/media 或 D:\\Media", value)
+	}
+	for _, part := range strings.FieldsFunc(dir, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return "", fmt.Errorf("目录 %q 不能包含 ..", value)
+		}
+	}
+
+	if trimmed := strings.TrimRight(dir, `/\`); trimmed != "" && !(windows && len(trimmed) == 2) {
+		dir = trimmed
+	}
+	return dir, nil
+}
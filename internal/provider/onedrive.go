@@ -3,8 +3,9 @@ package provider
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strconv"
+	"net/mail"
 	"strings"
 
 	"github.com/yzbtdiy/openlist_batch/internal/config"
@@ -13,16 +14,12 @@ import (
 
 // OneDriveApp OneDrive APP 提供商
 type OneDriveApp struct {
-	Region  string
-	Tenants []config.Tenant
+	cfg config.OneDriveApp
 }
 
 // NewOneDriveApp 创建 OneDrive 提供商
-func NewOneDriveApp(region string, tenants []config.Tenant) *OneDriveApp {
-	return &OneDriveApp{
-		Region:  region,
-		Tenants: tenants,
-	}
+func NewOneDriveApp(cfg config.OneDriveApp) *OneDriveApp {
+	return &OneDriveApp{cfg: cfg}
 }
 
 // Name 返回提供商名称
@@ -36,38 +33,32 @@ func (o *OneDriveApp) Driver() string {
 }
 
 // BuildRequest 构建存储挂载请求
-// emailInfo 格式: "tid:email:path" 或 "tid:email"
-func (o *OneDriveApp) BuildRequest(mountPath string, emailInfo string) (*model.StorageRequest, error) {
-	parts := strings.Split(emailInfo, ":")
-	if len(parts) < 2 || len(parts) > 3 {
-		return nil, fmt.Errorf("无效的 OneDrive 配置格式: %s, 应为 tid:email[:path]", emailInfo)
-	}
-
-	// 解析租户 ID 索引
-	tid, err := strconv.Atoi(parts[0])
+//
+// value 为结构化条目 (onedrive_app.yaml 中的映射) 或旧格式 "tenant:email[:path]",
+// 见 parseOneDriveEntry
+func (o *OneDriveApp) BuildRequest(mountPath string, value string) (*model.StorageRequest, error) {
+	entry, err := parseOneDriveEntry(value)
 	if err != nil {
-		return nil, fmt.Errorf("无效的租户 ID: %s", parts[0])
+		return nil, err
 	}
-
-	if tid < 1 || tid > len(o.Tenants) {
-		return nil, fmt.Errorf("租户 ID 超出范围: %d, 有效范围: 1-%d", tid, len(o.Tenants))
+	tenant, err := o.cfg.FindTenant(string(entry.Tenant))
+	if err != nil {
+		return nil, err
 	}
 
-	email := parts[1]
-	folderPath := "/"
-	if len(parts) == 3 {
-		folderPath = parts[2]
+	chunkSize := entry.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = tenant.UploadChunkSize()
 	}
 
-	tenant := o.Tenants[tid-1]
 	addition := model.OneDriveAppAddition{
-		RootFolderPath: folderPath,
-		Region:         o.Region,
+		RootFolderPath: entry.Path,
+		Region:         o.cfg.TenantRegion(tenant),
 		ClientId:       tenant.ClientID,
 		ClientSecret:   tenant.ClientSecret,
 		TenantId:       tenant.TenantID,
-		Email:          email,
-		ChunkSize:      5,
+		Email:          entry.Email,
+		ChunkSize:      chunkSize,
 	}
 
 	additionJSON, err := json.Marshal(addition)
@@ -91,3 +82,107 @@ func (o *OneDriveApp) BuildRequest(mountPath string, emailInfo string) (*model.S
 		Addition:        string(additionJSON),
 	}, nil
 }
+
+// BuildUpdateRequest 构建更新请求 (更新对应租户的 client_secret)
+//
+// 按附加信息中的 client_id 和 tenant_id 查找 config.yaml 中的租户
+func (o *OneDriveApp) BuildUpdateRequest(item model.StorageItem) (*model.StorageUpdateRequest, bool, error) {
+	var addition model.OneDriveAppAddition
+	if err := json.Unmarshal([]byte(item.Addition), &addition); err != nil {
+		return nil, false, fmt.Errorf("解析原有附加信息失败: %w", err)
+	}
+
+	for _, tenant := range o.cfg.Tenants {
+		if tenant.ClientID == addition.ClientId && tenant.TenantID == addition.TenantId {
+			return buildCredentialUpdate(item, map[string]any{"client_secret": tenant.ClientSecret})
+		}
+	}
+	return nil, false, fmt.Errorf("config.yaml 中没有 client_id 为 %s 的租户", addition.ClientId)
+}
+
+// oneDriveEntry onedrive_app.yaml 中的一条挂载
+type oneDriveEntry struct {
+	Tenant    tenantRef `json:"tenant"`
+	Email     string    `json:"email"`
+	Path      string    `json:"path"`
+	ChunkSize int       `json:"chunk_size"`
+}
+
+// tenantRef 租户引用, 按 config.OneDriveApp.FindTenant 查找
+type tenantRef string
+
+// UnmarshalJSON 同时接受字符串和数字
+func (r *tenantRef) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err == nil {
+		*r = tenantRef(n.String())
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("tenant 应为名称或序号")
+	}
+	*r = tenantRef(s)
+	return nil
+}
+
+// parseOneDriveEntry 解析挂载条目
+//
+// 结构化条目 (分享文件中的映射, 加载时编码为 JSON):
+//
+//	{tenant: 名称, email: 邮箱, path: /目录, chunk_size: 10}
+//
+// 旧格式 "tenant:email[:path]", path 中可以包含冒号
+func parseOneDriveEntry(value string) (oneDriveEntry, error) {
+	var entry oneDriveEntry
+	if v := strings.TrimSpace(value); strings.HasPrefix(v, "{") {
+		dec := json.NewDecoder(strings.NewReader(v))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&entry); err != nil {
+			return entry, fmt.Errorf("无效的 OneDrive 条目: %w", err)
+		}
+	} else {
+		parts := strings.SplitN(value, ":", 3)
+		if len(parts) < 2 {
+			return entry, fmt.Errorf("无效的 OneDrive 配置格式: %s, 应为 tenant:email[:path] 或 {tenant, email, path, chunk_size} 映射", value)
+		}
+		entry.Tenant, entry.Email = tenantRef(parts[0]), parts[1]
+		if len(parts) == 3 {
+			entry.Path = parts[2]
+		}
+	}
+
+	if entry.Tenant == "" {
+		return entry, fmt.Errorf("OneDrive 条目缺少 tenant")
+	}
+	if entry.Email == "" {
+		return entry, fmt.Errorf("OneDrive 条目缺少 email")
+	}
+	if entry.Path == "" {
+		entry.Path = "/"
+	}
+	return entry, nil
+}
+
+// Validate 静态检查 OneDrive 条目: 租户是否存在、邮箱格式、目录路径、分片大小
+func (o *OneDriveApp) Validate(value string) error {
+	entry, err := parseOneDriveEntry(value)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if _, err := o.cfg.FindTenant(string(entry.Tenant)); err != nil {
+		errs = append(errs, err)
+	}
+	if addr, err := mail.ParseAddress(entry.Email); err != nil || addr.Address != entry.Email {
+		errs = append(errs, fmt.Errorf("邮箱 %q 格式不正确", entry.Email))
+	}
+	if !strings.HasPrefix(entry.Path, "/") {
+		errs = append(errs, fmt.Errorf("目录 %q 应以 / 开头", entry.Path))
+	}
+	if entry.ChunkSize < 0 {
+		errs = append(errs, fmt.Errorf("chunk_size 不能为负数: %d", entry.ChunkSize))
+	}
+	return errors.Join(errs...)
+}
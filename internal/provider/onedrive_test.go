@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
+
+func TestOneDriveAppTenantLookup(t *testing.T) {
+	cfg := config.OneDriveApp{
+		Region: "global",
+		Tenants: []config.Tenant{
+			{ID: 7, Name: "2", ClientID: "cid-a", TenantID: "tid-a"},
+			{Name: "contoso", ClientID: "cid-b", TenantID: "tid-b", Region: "cn"},
+			{ClientID: "cid-c", TenantID: "tid-c"},
+		},
+	}
+	p := NewOneDriveApp(cfg)
+
+	tests := []struct {
+		name     string
+		value    string
+		tenantID string
+		region   string
+	}{
+		{"name before index", `{"tenant":"2","email":"u@example.com"}`, "tid-a", "global"},
+		{"numeric name", `{"tenant":2,"email":"u@example.com"}`, "tid-a", "global"},
+		{"name with tenant region", `{"tenant":"contoso","email":"u@example.com"}`, "tid-b", "cn"},
+		{"id", `{"tenant":7,"email":"u@example.com"}`, "tid-a", "global"},
+		{"index", "3:u@example.com", "tid-c", "global"},
+		{"tenant id", `{"tenant":"tid-b","email":"u@example.com"}`, "tid-b", "cn"},
+		{"client id", "cid-c:u@example.com:/Docs", "tid-c", "global"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := p.BuildRequest("/OneDrive/u", tt.value)
+			if err != nil {
+				t.Fatalf("BuildRequest error: %v", err)
+			}
+			var a model.OneDriveAppAddition
+			if err := json.Unmarshal([]byte(req.Addition), &a); err != nil {
+				t.Fatalf("addition: %v", err)
+			}
+			if a.TenantId != tt.tenantID || a.Region != tt.region {
+				t.Errorf("tenant %q region %q, want %q %q", a.TenantId, a.Region, tt.tenantID, tt.region)
+			}
+
+			// 与 tenant、users 命令查找到同一个租户
+			want, err := cfg.FindTenant(cfg.TenantRef(mustTenant(t, cfg, tt.tenantID)))
+			if err != nil || want.TenantID != tt.tenantID {
+				t.Errorf("FindTenant(TenantRef) = %q, %v, want %q", want.TenantID, err, tt.tenantID)
+			}
+		})
+	}
+
+	if err := p.Validate(`{"tenant":"nobody","email":"u@example.com"}`); err == nil {
+		t.Error("Validate accepted an unknown tenant")
+	}
+}
+
+// mustTenant 按 tenant_id 返回配置中的租户
+func mustTenant(t *testing.T, cfg config.OneDriveApp, tenantID string) config.Tenant {
+	t.Helper()
+	for _, tenant := range cfg.Tenants {
+		if tenant.TenantID == tenantID {
+			return tenant
+		}
+	}
+	t.Fatalf("no tenant %s", tenantID)
+	return config.Tenant{}
+}
@@ -1,25 +1,30 @@
-// Package provider 提供 PikPak 存储支持
+// Package provider 提供 PikPak 分享与账户存储支持
 package provider
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strings"
 
+	"github.com/yzbtdiy/openlist_batch/internal/config"
 	"github.com/yzbtdiy/openlist_batch/internal/model"
 )
 
 // PikPakShare 提供商
 type PikPakShare struct {
 	Platform              string
+	DeviceID              string
 	UseTranscodingAddress bool
 }
 
 // NewPikPakShare 创建 PikPakShare 提供商
-func NewPikPakShare(platform string, useTranscoding bool) *PikPakShare {
+func NewPikPakShare(platform, deviceID string, useTranscoding bool) *PikPakShare {
 	return &PikPakShare{
 		Platform:              platform,
+		DeviceID:              deviceID,
 		UseTranscodingAddress: useTranscoding,
 	}
 }
@@ -36,24 +41,9 @@ func (p *PikPakShare) Driver() string {
 
 // BuildRequest 构建存储挂载请求
 func (p *PikPakShare) BuildRequest(mountPath string, shareURL string) (*model.StorageRequest, error) {
-	parsed, err := url.Parse(shareURL)
+	shareID, folderID, sharePwd, err := parsePikPakShareURL(shareURL)
 	if err != nil {
-		return nil, fmt.Errorf("解析分享链接失败: %w", err)
-	}
-
-	// 提取分享密码
-	sharePwd := parsed.Query().Get("pwd")
-
-	// 解析路径: /s/shareId 或 /s/shareId/folderId
-	pathParts := strings.Split(parsed.Path, "/")
-	if len(pathParts) < 3 {
-		return nil, fmt.Errorf("无效的 PikPak 分享链接格式")
-	}
-
-	shareID := pathParts[2]
-	folderID := ""
-	if len(pathParts) >= 4 {
-		folderID = pathParts[3]
+		return nil, err
 	}
 
 	addition := model.PikPakShareAddition{
@@ -61,7 +51,7 @@ func (p *PikPakShare) BuildRequest(mountPath string, shareURL string) (*model.St
 		ShareId:               shareID,
 		SharePwd:              sharePwd,
 		Platform:              p.Platform,
-		DeviceId:              "",
+		DeviceId:              p.DeviceID,
 		UseTranscodingAddress: p.UseTranscodingAddress,
 	}
 
@@ -88,3 +78,278 @@ func (p *PikPakShare) BuildRequest(mountPath string, shareURL string) (*model.St
 		Addition:         string(additionJSON),
 	}, nil
 }
+
+// BuildUpdateRequest 构建更新请求 (更新设备平台、设备 ID 和转码地址设置)
+//
+// 未配置设备 ID 时保留 OpenList 已生成的设备 ID
+func (p *PikPakShare) BuildUpdateRequest(item model.StorageItem) (*model.StorageUpdateRequest, bool, error) {
+	credentials := map[string]any{
+		"platform":                p.Platform,
+		"use_transcoding_address": p.UseTranscodingAddress,
+	}
+	if p.DeviceID != "" {
+		credentials["device_id"] = p.DeviceID
+	}
+	return buildCredentialUpdate(item, credentials)
+}
+
+// pikpakHosts PikPak 分享链接的域名
+var pikpakHosts = []string{"mypikpak.com", "www.mypikpak.com"}
+
+// pikpakIDPattern PikPak 分享 ID 与文件夹 ID 的格式
+var pikpakIDPattern = regexp.MustCompile(`^[0-9A-Za-z_-]{16,40}$`)
+
+// parsePikPakShareURL 解析分享链接: https://mypikpak.com/s/shareId[/folderId]?pwd=xxxx
+func parsePikPakShareURL(shareURL string) (shareID, folderID, sharePwd string, err error) {
+	parsed, err := url.Parse(strings.TrimSpace(shareURL))
+	if err != nil {
+		return "", "", "", fmt.Errorf("解析分享链接失败: %w", err)
+	}
+
+	// 解析路径: /s/shareId 或 /s/shareId/folderId
+	pathParts := strings.Split(strings.TrimSuffix(parsed.Path, "/"), "/")
+	if len(pathParts) < 3 || len(pathParts) > 4 || pathParts[1] != "s" || pathParts[2] == "" {
+		return "", "", "", fmt.Errorf("无效的 PikPak 分享链接格式, 应为 /s/分享ID[/文件夹ID]")
+	}
+	if len(pathParts) == 4 {
+		folderID = pathParts[3]
+	}
+	return pathParts[2], folderID, parsed.Query().Get("pwd"), nil
+}
+
+// Validate 静态检查分享链接: 域名、分享 ID 与文件夹 ID 格式、提取码长度
+func (p *PikPakShare) Validate(shareURL string) error {
+	shareID, folderID, sharePwd, err := parsePikPakShareURL(shareURL)
+	if err != nil {
+		return err
+	}
+
+	parsed, _ := url.Parse(strings.TrimSpace(shareURL))
+	var errs []error
+	if err := checkShareHost(parsed, pikpakHosts); err != nil {
+		errs = append(errs, err)
+	}
+	if !pikpakIDPattern.MatchString(shareID) {
+		errs = append(errs, fmt.Errorf("分享 ID %q 格式不正确", shareID))
+	}
+	if folderID != "" && !pikpakIDPattern.MatchString(folderID) {
+		errs = append(errs, fmt.Errorf("文件夹 ID %q 格式不正确", folderID))
+	}
+	if err := checkSharePwd(sharePwd); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// PikPak 账户提供商, 挂载账户自己的网盘
+type PikPak struct {
+	Accounts []config.PikPakAccount
+}
+
+// NewPikPak 创建 PikPak 账户提供商
+func NewPikPak(accounts []config.PikPakAccount) *PikPak {
+	return &PikPak{Accounts: accounts}
+}
+
+// Name 返回提供商名称
+func (p *PikPak) Name() string {
+	return "PikPak 网盘"
+}
+
+// Driver 返回 OpenList 驱动名称
+func (p *PikPak) Driver() string {
+	return "PikPak"
+}
+
+// BuildRequest 构建存储挂载请求
+//
+// value 为 "[账户:]文件夹", 见 parsePikPakEntry; 以路径指定的文件夹需要先用
+// FolderPath 和 WithFolderID 换成文件夹 ID
+func (p *PikPak) BuildRequest(mountPath string, value string) (*model.StorageRequest, error) {
+	entry, err := parsePikPakEntry(value)
+	if err != nil {
+		return nil, err
+	}
+	if entry.dir != "" {
+		return nil, fmt.Errorf("文件夹路径 %s 需要连接 OpenList 解析为文件夹 ID", entry.dir)
+	}
+	return p.buildRequest(mountPath, entry.account, entry.folderID)
+}
+
+// FolderPath 返回条目中以路径指定的文件夹, 以及挂载该账户网盘根目录的请求
+func (p *PikPak) FolderPath(value string) (*model.StorageRequest, string, error) {
+	entry, err := parsePikPakEntry(value)
+	if err != nil || entry.dir == "" {
+		return nil, "", err
+	}
+	root, err := p.buildRequest("", entry.account, "")
+	if err != nil {
+		return nil, "", err
+	}
+	return root, entry.dir, nil
+}
+
+// WithFolderID 返回把路径换成文件夹 ID 后的条目, 保留账户前缀
+func (p *PikPak) WithFolderID(value, folderID string) (string, error) {
+	entry, err := parsePikPakEntry(value)
+	if err != nil {
+		return "", err
+	}
+	if entry.account == "" {
+		return folderID, nil
+	}
+	return entry.account + ":" + folderID, nil
+}
+
+// buildRequest 按账户和文件夹 ID 构建存储挂载请求, 文件夹 ID 为空表示根目录
+func (p *PikPak) buildRequest(mountPath, ref, folderID string) (*model.StorageRequest, error) {
+	account, err := p.findAccount(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	addition := model.PikPakAddition{
+		RootFolderId:     folderID,
+		Username:         account.Username,
+		Password:         account.Password,
+		Platform:         account.Platform,
+		RefreshToken:     account.RefreshToken,
+		DeviceId:         account.DeviceID,
+		DisableMediaLink: true,
+	}
+
+	additionJSON, err := json.Marshal(addition)
+	if err != nil {
+		return nil, fmt.Errorf("序列化附加信息失败: %w", err)
+	}
+
+	return &model.StorageRequest{
+		MountPath:       mountPath,
+		Order:           0,
+		Remark:          "",
+		CacheExpiration: 30,
+		WebProxy:        false,
+		WebdavPolicy:    "302_redirect",
+		DownProxyUrl:    "",
+		OrderBy:         "",
+		OrderDirection:  "",
+		ExtractFolder:   "",
+		EnableSign:      false,
+		Driver:          p.Driver(),
+		Addition:        string(additionJSON),
+	}, nil
+}
+
+// BuildUpdateRequest 构建更新请求 (更新对应账户的 refresh_token、密码、平台和设备 ID)
+//
+// 按附加信息中的 username 查找 config.yaml 中的账户; 账户未配置的字段保留原值,
+// 因此只配置了密码时 OpenList 轮换后的 refresh_token 不会被覆盖
+func (p *PikPak) BuildUpdateRequest(item model.StorageItem) (*model.StorageUpdateRequest, bool, error) {
+	var addition model.PikPakAddition
+	if err := json.Unmarshal([]byte(item.Addition), &addition); err != nil {
+		return nil, false, fmt.Errorf("解析原有附加信息失败: %w", err)
+	}
+
+	for _, account := range p.Accounts {
+		if account.Username != addition.Username {
+			continue
+		}
+		credentials := map[string]any{"platform": account.Platform}
+		if account.Password != "" {
+			credentials["password"] = account.Password
+		}
+		if account.RefreshToken != "" {
+			credentials["refresh_token"] = account.RefreshToken
+		}
+		if account.DeviceID != "" {
+			credentials["device_id"] = account.DeviceID
+		}
+		return buildCredentialUpdate(item, credentials)
+	}
+	return nil, false, fmt.Errorf("config.yaml 中没有 username 为 %s 的 PikPak 账户", addition.Username)
+}
+
+// Validate 静态检查挂载条目: 账户存在、文件夹 ID 格式; 不检查文件夹路径是否存在
+func (p *PikPak) Validate(value string) error {
+	entry, err := parsePikPakEntry(value)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if _, err := p.findAccount(entry.account); err != nil {
+		errs = append(errs, err)
+	}
+	if entry.folderID != "" && !pikpakIDPattern.MatchString(entry.folderID) {
+		errs = append(errs, fmt.Errorf("文件夹 ID %q 格式不正确", entry.folderID))
+	}
+	return errors.Join(errs...)
+}
+
+// findAccount 按名称查找账户, 名称为空时使用唯一的账户或 default 账户
+func (p *PikPak) findAccount(ref string) (config.PikPakAccount, error) {
+	if ref == "" {
+		if len(p.Accounts) == 1 {
+			return p.Accounts[0], nil
+		}
+		ref = config.PikPakDefaultAccount
+	}
+	for _, account := range p.Accounts {
+		if account.Name == ref {
+			return account, nil
+		}
+	}
+	return config.PikPakAccount{}, fmt.Errorf("pikpak 中没有账户 %s", ref)
+}
+
+// pikpakEntry PikPak 网盘挂载条目
+type pikpakEntry struct {
+	account  string // 为空时使用唯一的账户或 default 账户
+	folderID string // 为空表示根目录
+	dir      string // 以路径指定的子文件夹, 不为空时 folderID 为空
+}
+
+// parsePikPakEntry 解析挂载条目 "[账户:]文件夹"
+//
+// 文件夹为 / (根目录)、/ 开头的子文件夹路径、文件夹 ID, 或网页版中文件夹的地址
+// (https://mypikpak.com/drive/all/文件夹ID); 省略账户时使用唯一的账户或 default 账户
+func parsePikPakEntry(value string) (pikpakEntry, error) {
+	var entry pikpakEntry
+	value = strings.TrimSpace(value)
+	if head, rest, ok := strings.Cut(value, ":"); ok && head != "http" && head != "https" {
+		entry.account, value = strings.TrimSpace(head), strings.TrimSpace(rest)
+	}
+
+	if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+		parsed, err := url.Parse(value)
+		if err != nil {
+			return entry, fmt.Errorf("解析文件夹地址失败: %w", err)
+		}
+		if err := checkShareHost(parsed, pikpakHosts); err != nil {
+			return entry, err
+		}
+		parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+		if len(parts) < 2 || parts[0] != "drive" {
+			return entry, fmt.Errorf("无效的 PikPak 文件夹地址, 应为 /drive/all/文件夹ID")
+		}
+		if last := parts[len(parts)-1]; last != "all" {
+			entry.folderID = last
+		}
+		return entry, nil
+	}
+
+	dir, err := parseFolderPath(value)
+	if err != nil {
+		return entry, err
+	}
+	switch {
+	case dir == "":
+		entry.folderID = strings.TrimSuffix(value, "/")
+		if strings.Contains(entry.folderID, "/") {
+			return entry, fmt.Errorf("文件夹 %q 应为文件夹 ID 或以 / 开头的路径", value)
+		}
+	case dir != "/":
+		entry.dir = dir
+	}
+	return entry, nil
+}
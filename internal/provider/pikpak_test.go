@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
+
+const testPikPakFolderID = "VNabcdefghijklmnopqrstuv"
+
+func TestParsePikPakEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    pikpakEntry
+		wantErr bool
+	}{
+		{name: "root", value: "/", want: pikpakEntry{}},
+		{name: "empty", value: "", want: pikpakEntry{}},
+		{name: "account root", value: "backup:/", want: pikpakEntry{account: "backup"}},
+		{name: "folder id", value: testPikPakFolderID, want: pikpakEntry{folderID: testPikPakFolderID}},
+		{name: "folder id with trailing slash", value: testPikPakFolderID + "/", want: pikpakEntry{folderID: testPikPakFolderID}},
+		{name: "account folder id", value: "backup:" + testPikPakFolderID, want: pikpakEntry{account: "backup", folderID: testPikPakFolderID}},
+		{name: "path", value: "/电影/国产", want: pikpakEntry{dir: "/电影/国产"}},
+		{name: "path with trailing slash", value: "/电影/", want: pikpakEntry{dir: "/电影"}},
+		{name: "account path", value: "backup: /电影//国产", want: pikpakEntry{account: "backup", dir: "/电影/国产"}},
+		{name: "drive url", value: "https://mypikpak.com/drive/all/" + testPikPakFolderID, want: pikpakEntry{folderID: testPikPakFolderID}},
+		{name: "drive url root", value: "https://mypikpak.com/drive/all", want: pikpakEntry{}},
+		{name: "account drive url", value: "backup:https://mypikpak.com/drive/all/" + testPikPakFolderID, want: pikpakEntry{account: "backup", folderID: testPikPakFolderID}},
+		{name: "path with dot dot", value: "/电影/../音乐", wantErr: true},
+		{name: "relative path", value: "电影/国产", wantErr: true},
+		{name: "other host", value: "https://example.com/drive/all/" + testPikPakFolderID, wantErr: true},
+		{name: "share url", value: "https://mypikpak.com/s/" + testPikPakFolderID, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePikPakEntry(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parsePikPakEntry(%q) = %+v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePikPakEntry(%q) error: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("parsePikPakEntry(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPikPakFolderPath(t *testing.T) {
+	p := NewPikPak([]config.PikPakAccount{
+		{Name: "default", Username: "a@example.com", RefreshToken: "token-a"},
+		{Name: "backup", Username: "b@example.com", RefreshToken: "token-b"},
+	})
+
+	root, dir, err := p.FolderPath("backup:/电影/国产")
+	if err != nil {
+		t.Fatalf("FolderPath error: %v", err)
+	}
+	if dir != "/电影/国产" {
+		t.Errorf("FolderPath dir = %q, want /电影/国产", dir)
+	}
+	var a model.PikPakAddition
+	if err := json.Unmarshal([]byte(root.Addition), &a); err != nil {
+		t.Fatalf("addition: %v", err)
+	}
+	if a.RootFolderId != "" || a.Username != "b@example.com" {
+		t.Errorf("root addition = folder %q user %q, want root of b@example.com", a.RootFolderId, a.Username)
+	}
+
+	if _, dir, err := p.FolderPath(testPikPakFolderID); err != nil || dir != "" {
+		t.Errorf("FolderPath(id) = dir %q err %v, want no path", dir, err)
+	}
+	if _, err := p.BuildRequest("/PikPak/电影", "/电影"); err == nil {
+		t.Error("BuildRequest accepted an unresolved path")
+	}
+
+	for value, want := range map[string]string{
+		"/电影":        testPikPakFolderID,
+		"backup:/电影": "backup:" + testPikPakFolderID,
+	} {
+		got, err := p.WithFolderID(value, testPikPakFolderID)
+		if err != nil || got != want {
+			t.Errorf("WithFolderID(%q) = %q, %v, want %q", value, got, err, want)
+		}
+		if _, err := p.BuildRequest("/PikPak/电影", got); err != nil {
+			t.Errorf("BuildRequest(%q) error: %v", got, err)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+// Package provider 提供 WebDAV、S3、SFTP、FTP、SMB 协议存储支持
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
+
+// SecretFunc 解析条目中的敏感字段 (${ENV}、secret:NAME 或 *_file 指定的文件)
+type SecretFunc func(value, file string) (string, error)
+
+// protocolDrivers 条目类型对应的 OpenList 驱动
+var protocolDrivers = map[string]string{
+	"webdav": "WebDav",
+	"s3":     "S3",
+	"sftp":   "SFTP",
+	"ftp":    "FTP",
+	"smb":    "SMB",
+}
+
+// protocolTypes 条目类型, 按显示顺序
+var protocolTypes = []string{"webdav", "s3", "sftp", "ftp", "smb"}
+
+// protocolPorts 未写端口时使用的默认端口
+var protocolPorts = map[string]string{"sftp": "22", "ftp": "21", "smb": "445"}
+
+// Protocol 协议存储提供商, 每个条目按 type 选择驱动
+type Protocol struct {
+	secret SecretFunc
+}
+
+// NewProtocol 创建协议存储提供商, secret 为 nil 时敏感字段按原值使用
+func NewProtocol(secret SecretFunc) *Protocol {
+	return &Protocol{secret: secret}
+}
+
+// Name 返回提供商名称
+func (p *Protocol) Name() string {
+	return "协议存储"
+}
+
+// Driver 返回 OpenList 驱动名称; 驱动由条目决定, 因此为空, 见 Drivers
+func (p *Protocol) Driver() string {
+	return ""
+}
+
+// Drivers 返回条目可能使用的所有驱动
+func (p *Protocol) Drivers() []string {
+	drivers := make([]string, 0, len(protocolTypes))
+	for _, t := range protocolTypes {
+		drivers = append(drivers, protocolDrivers[t])
+	}
+	return drivers
+}
+
+// protocolEntry storages.yaml 中的一条协议存储
+type protocolEntry struct {
+	Type         string `json:"type"`
+	Host         string `json:"host"` // WebDAV 地址、S3 endpoint 或 host[:port]
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	PasswordFile string `json:"password_file"`
+	Root         string `json:"root"`
+
+	// WebDAV
+	Vendor   string `json:"vendor"`
+	Insecure bool   `json:"insecure"`
+
+	// S3
+	Bucket              string `json:"bucket"`
+	Region              string `json:"region"`
+	AccessKeyID         string `json:"access_key_id"`
+	SecretAccessKey     string `json:"secret_access_key"`
+	SecretAccessKeyFile string `json:"secret_access_key_file"`
+	ForcePathStyle      bool   `json:"force_path_style"`
+	CustomHost          string `json:"custom_host"`
+
+	// SFTP
+	PrivateKey     string `json:"private_key"`
+	PrivateKeyFile string `json:"private_key_file"`
+	Passphrase     string `json:"passphrase"`
+
+	// FTP
+	Encoding string `json:"encoding"`
+
+	// SMB
+	Share string `json:"share"`
+}
+
+// BuildRequest 构建存储挂载请求
+//
+// value 为 storages.yaml 中的映射 (加载时编码为 JSON), 见 parseProtocolEntry
+func (p *Protocol) BuildRequest(mountPath string, value string) (*model.StorageRequest, error) {
+	entry, err := parseProtocolEntry(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := entry.check(); err != nil {
+		return nil, err
+	}
+	if err := p.resolveSecrets(&entry); err != nil {
+		return nil, err
+	}
+
+	var addition any
+	switch entry.Type {
+	case "webdav":
+		addition = model.WebDavAddition{
+			Vendor:                config.OrDefault(entry.Vendor, "other"),
+			Address:               entry.Host,
+			Username:              entry.Username,
+			Password:              entry.Password,
+			RootFolderPath:        entry.Root,
+			TlsInsecureSkipVerify: entry.Insecure,
+		}
+	case "s3":
+		addition = model.S3Addition{
+			RootFolderPath:    entry.Root,
+			Bucket:            entry.Bucket,
+			Endpoint:          entry.Host,
+			Region:            entry.Region,
+			AccessKeyID:       entry.AccessKeyID,
+			SecretAccessKey:   entry.SecretAccessKey,
+			CustomHost:        entry.CustomHost,
+			SignURLExpire:     4,
+			ForcePathStyle:    entry.ForcePathStyle,
+			ListObjectVersion: "v1",
+		}
+	case "sftp":
+		addition = model.SFTPAddition{
+			Address:        entry.address(),
+			Username:       entry.Username,
+			PrivateKey:     entry.PrivateKey,
+			Password:       entry.Password,
+			Passphrase:     entry.Passphrase,
+			RootFolderPath: entry.Root,
+		}
+	case "ftp":
+		addition = model.FTPAddition{
+			Address:        entry.address(),
+			Encoding:       config.OrDefault(entry.Encoding, "UTF-8"),
+			Username:       entry.Username,
+			Password:       entry.Password,
+			RootFolderPath: entry.Root,
+		}
+	case "smb":
+		addition = model.SMBAddition{
+			RootFolderPath: entry.Root,
+			Address:        entry.address(),
+			Username:       entry.Username,
+			Password:       entry.Password,
+			ShareName:      entry.Share,
+		}
+	}
+
+	additionJSON, err := json.Marshal(addition)
+	if err != nil {
+		return nil, fmt.Errorf("序列化附加信息失败: %w", err)
+	}
+
+	return &model.StorageRequest{
+		MountPath:       mountPath,
+		Order:           0,
+		Remark:          "",
+		CacheExpiration: 30,
+		WebProxy:        false,
+		WebdavPolicy:    "native_proxy",
+		DownProxyUrl:    "",
+		OrderBy:         "",
+		OrderDirection:  "",
+		ExtractFolder:   "",
+		EnableSign:      false,
+		Driver:          protocolDrivers[entry.Type],
+		Addition:        string(additionJSON),
+	}, nil
+}
+
+// Validate 静态检查条目: 类型、必填字段、地址与根目录格式; 不解析敏感字段的引用
+func (p *Protocol) Validate(value string) error {
+	entry, err := parseProtocolEntry(value)
+	if err != nil {
+		return err
+	}
+	return entry.check()
+}
+
+// parseProtocolEntry 解析条目, 不允许未知字段; root 默认为 /
+//
+//	{type: webdav, host: https://nas:5006, username: admin, password: ${NAS_PASSWORD}, root: /photo}
+//	{type: s3, host: http://minio:9000, bucket: backup, access_key_id: xxx, secret_access_key: xxx}
+//	{type: sftp, host: nas:22, username: admin, private_key_file: id_ed25519}
+//	{type: ftp, host: nas, username: admin, password: xxx}
+//	{type: smb, host: nas, share: media, username: admin, password: xxx}
+func parseProtocolEntry(value string) (protocolEntry, error) {
+	var entry protocolEntry
+	v := strings.TrimSpace(value)
+	if !strings.HasPrefix(v, "{") {
+		return entry, fmt.Errorf("协议存储条目应为包含 type、host 等字段的映射")
+	}
+	dec := json.NewDecoder(strings.NewReader(v))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&entry); err != nil {
+		return entry, fmt.Errorf("无效的协议存储条目: %w", err)
+	}
+
+	entry.Type = strings.ToLower(entry.Type)
+	if entry.Root = strings.TrimSuffix(entry.Root, "/"); entry.Root == "" {
+		entry.Root = "/"
+	}
+	return entry, nil
+}
+
+// check 按类型检查必填字段和格式
+func (e protocolEntry) check() error {
+	if _, ok := protocolDrivers[e.Type]; !ok {
+		return fmt.Errorf("未知的 type %q, 可选值: %s", e.Type, strings.Join(protocolTypes, ", "))
+	}
+
+	var errs []error
+	require := func(name, value string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s 类型需要 %s", e.Type, name))
+		}
+	}
+	require("host", e.Host)
+	if !strings.HasPrefix(e.Root, "/") || path.Clean(e.Root) != e.Root {
+		errs = append(errs, fmt.Errorf("root %q 应以 / 开头且不包含多余的 / 或 ..", e.Root))
+	}
+
+	switch e.Type {
+	case "webdav", "s3":
+		if e.Host != "" {
+			if u, err := url.Parse(e.Host); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, fmt.Errorf("host %q 应为 http:// 或 https:// 开头的地址", e.Host))
+			}
+		}
+		if e.Type == "webdav" {
+			require("username", e.Username)
+			if e.Vendor != "" && e.Vendor != "other" && e.Vendor != "sharepoint" {
+				errs = append(errs, fmt.Errorf("vendor %q 应为 other 或 sharepoint", e.Vendor))
+			}
+		} else {
+			require("bucket", e.Bucket)
+			require("access_key_id", e.AccessKeyID)
+			if e.SecretAccessKey == "" && e.SecretAccessKeyFile == "" {
+				errs = append(errs, fmt.Errorf("s3 类型需要 secret_access_key 或 secret_access_key_file"))
+			}
+		}
+	default:
+		if _, _, err := splitHostPort(e.Host); e.Host != "" && err != nil {
+			errs = append(errs, err)
+		}
+		require("username", e.Username)
+		if e.Type == "sftp" && e.Password == "" && e.PasswordFile == "" && e.PrivateKey == "" && e.PrivateKeyFile == "" {
+			errs = append(errs, fmt.Errorf("sftp 类型需要 password 或 private_key"))
+		}
+		if e.Type == "smb" {
+			require("share", e.Share)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// address 返回 host:port, 未写端口时使用默认端口
+func (e protocolEntry) address() string {
+	host, port, err := splitHostPort(e.Host)
+	if err != nil {
+		return e.Host
+	}
+	if port == "" {
+		port = protocolPorts[e.Type]
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// splitHostPort 拆分 host[:port], 允许带 sftp:// 等协议前缀
+func splitHostPort(hostport string) (host, port string, err error) {
+	if _, rest, ok := strings.Cut(hostport, "://"); ok {
+		hostport = rest
+	}
+	hostport = strings.TrimSuffix(hostport, "/")
+	if strings.Contains(hostport, "/") {
+		return "", "", fmt.Errorf("host %q 应为 主机[:端口], 目录请写在 root 中", hostport)
+	}
+
+	host, port, err = net.SplitHostPort(hostport)
+	if err != nil {
+		// 没有端口
+		host, port = strings.Trim(hostport, "[]"), ""
+	}
+	if host == "" {
+		return "", "", fmt.Errorf("host %q 缺少主机名", hostport)
+	}
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", "", fmt.Errorf("host %q 中的端口无效", hostport)
+		}
+	}
+	return host, port, nil
+}
+
+// resolveSecrets 解析条目中的 password、secret_access_key、private_key 引用
+func (p *Protocol) resolveSecrets(e *protocolEntry) error {
+	if p.secret == nil {
+		return nil
+	}
+	fields := []struct {
+		name  string
+		value *string
+		file  string
+	}{
+		{"password", &e.Password, e.PasswordFile},
+		{"secret_access_key", &e.SecretAccessKey, e.SecretAccessKeyFile},
+		{"private_key", &e.PrivateKey, e.PrivateKeyFile},
+		{"passphrase", &e.Passphrase, ""},
+	}
+	for _, f := range fields {
+		if *f.value == "" && f.file == "" {
+			continue
+		}
+		v, err := p.secret(*f.value, f.file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+		*f.value = v
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
+
+func TestProtocolValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"webdav", `{"type":"webdav","host":"https://nas:5006","username":"admin","password":"x"}`, false},
+		{"webdav sharepoint", `{"type":"webdav","host":"https://a.sharepoint.com","username":"u","vendor":"sharepoint"}`, false},
+		{"webdav type case", `{"type":"WebDAV","host":"http://nas","username":"admin"}`, false},
+		{"webdav missing host", `{"type":"webdav","username":"admin"}`, true},
+		{"webdav missing username", `{"type":"webdav","host":"https://nas"}`, true},
+		{"webdav host without scheme", `{"type":"webdav","host":"nas:5006","username":"admin"}`, true},
+		{"webdav unknown vendor", `{"type":"webdav","host":"https://nas","username":"admin","vendor":"nextcloud"}`, true},
+		{"s3", `{"type":"s3","host":"http://minio:9000","bucket":"b","access_key_id":"k","secret_access_key":"s"}`, false},
+		{"s3 secret file", `{"type":"s3","host":"http://minio:9000","bucket":"b","access_key_id":"k","secret_access_key_file":"s3.key"}`, false},
+		{"s3 missing bucket", `{"type":"s3","host":"http://minio:9000","access_key_id":"k","secret_access_key":"s"}`, true},
+		{"s3 missing access key", `{"type":"s3","host":"http://minio:9000","bucket":"b","secret_access_key":"s"}`, true},
+		{"s3 missing secret", `{"type":"s3","host":"http://minio:9000","bucket":"b","access_key_id":"k"}`, true},
+		{"sftp password", `{"type":"sftp","host":"nas","username":"admin","password":"x"}`, false},
+		{"sftp key file", `{"type":"sftp","host":"sftp://nas:2222","username":"admin","private_key_file":"id_ed25519"}`, false},
+		{"sftp without credentials", `{"type":"sftp","host":"nas","username":"admin"}`, true},
+		{"sftp bad port", `{"type":"sftp","host":"nas:70000","username":"admin","password":"x"}`, true},
+		{"sftp host with path", `{"type":"sftp","host":"nas/data","username":"admin","password":"x"}`, true},
+		{"ftp", `{"type":"ftp","host":"nas","username":"admin","password":"x"}`, false},
+		{"ftp missing username", `{"type":"ftp","host":"nas"}`, true},
+		{"smb", `{"type":"smb","host":"nas","share":"media","username":"admin","password":"x"}`, false},
+		{"smb missing share", `{"type":"smb","host":"nas","username":"admin","password":"x"}`, true},
+		{"root", `{"type":"ftp","host":"nas","username":"admin","root":"/data/"}`, false},
+		{"relative root", `{"type":"ftp","host":"nas","username":"admin","root":"data"}`, true},
+		{"root with dot dot", `{"type":"ftp","host":"nas","username":"admin","root":"/data/../etc"}`, true},
+		{"root with double slash", `{"type":"ftp","host":"nas","username":"admin","root":"//data"}`, true},
+		{"unknown type", `{"type":"nfs","host":"nas","username":"admin"}`, true},
+		{"missing type", `{"host":"nas","username":"admin"}`, true},
+		{"unknown field", `{"type":"ftp","host":"nas","username":"admin","port":21}`, true},
+		{"not a mapping", `ftp://nas`, true},
+	}
+
+	p := NewProtocol(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Validate(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%s) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProtocolAddress(t *testing.T) {
+	tests := []struct {
+		typ  string
+		host string
+		want string
+	}{
+		{"sftp", "nas", "nas:22"},
+		{"sftp", "sftp://nas:2222", "nas:2222"},
+		{"ftp", "nas", "nas:21"},
+		{"ftp", "ftp://192.168.1.2/", "192.168.1.2:21"},
+		{"smb", "nas", "nas:445"},
+		{"smb", "[fd00::2]", "[fd00::2]:445"},
+		{"smb", "[fd00::2]:1445", "[fd00::2]:1445"},
+	}
+
+	for _, tt := range tests {
+		if got := (protocolEntry{Type: tt.typ, Host: tt.host}).address(); got != tt.want {
+			t.Errorf("address(%s %q) = %q, want %q", tt.typ, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestProtocolBuildRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		driver string
+		want   any
+	}{
+		{
+			name:   "webdav",
+			value:  `{"type":"webdav","host":"https://nas:5006","username":"admin","password":"${NAS}","root":"/photo/","insecure":true}`,
+			driver: "WebDav",
+			want: model.WebDavAddition{Vendor: "other", Address: "https://nas:5006", Username: "admin",
+				Password: "resolved:${NAS}", RootFolderPath: "/photo", TlsInsecureSkipVerify: true},
+		},
+		{
+			name:   "s3",
+			value:  `{"type":"s3","host":"http://minio:9000","bucket":"b","region":"us-east-1","access_key_id":"k","secret_access_key_file":"s3.key","force_path_style":true}`,
+			driver: "S3",
+			want: model.S3Addition{RootFolderPath: "/", Bucket: "b", Endpoint: "http://minio:9000", Region: "us-east-1",
+				AccessKeyID: "k", SecretAccessKey: "resolved:s3.key", SignURLExpire: 4, ForcePathStyle: true, ListObjectVersion: "v1"},
+		},
+		{
+			name:   "sftp",
+			value:  `{"type":"sftp","host":"nas","username":"admin","private_key_file":"id_ed25519","passphrase":"p","root":"/data"}`,
+			driver: "SFTP",
+			want: model.SFTPAddition{Address: "nas:22", Username: "admin", PrivateKey: "resolved:id_ed25519",
+				Passphrase: "resolved:p", RootFolderPath: "/data"},
+		},
+		{
+			name:   "ftp",
+			value:  `{"type":"ftp","host":"nas:2121","username":"admin","password":"x"}`,
+			driver: "FTP",
+			want:   model.FTPAddition{Address: "nas:2121", Encoding: "UTF-8", Username: "admin", Password: "resolved:x", RootFolderPath: "/"},
+		},
+		{
+			name:   "smb",
+			value:  `{"type":"smb","host":"nas","share":"media","username":"admin","password":"x"}`,
+			driver: "SMB",
+			want:   model.SMBAddition{RootFolderPath: "/", Address: "nas:445", Username: "admin", Password: "resolved:x", ShareName: "media"},
+		},
+	}
+
+	p := NewProtocol(func(value, file string) (string, error) {
+		if file != "" {
+			return "resolved:" + file, nil
+		}
+		return "resolved:" + value, nil
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := p.BuildRequest("/NAS/"+tt.name, tt.value)
+			if err != nil {
+				t.Fatalf("BuildRequest error: %v", err)
+			}
+			if req.Driver != tt.driver || req.MountPath != "/NAS/"+tt.name {
+				t.Errorf("BuildRequest = driver %q mount %q, want %q", req.Driver, req.MountPath, tt.driver)
+			}
+			want, err := json.Marshal(tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.Addition != string(want) {
+				t.Errorf("addition = %s\nwant %s", req.Addition, want)
+			}
+		})
+	}
+}
+
+func TestProtocolBuildRequestErrors(t *testing.T) {
+	failing := NewProtocol(func(value, file string) (string, error) {
+		return "", errors.New("secret not found")
+	})
+	if _, err := failing.BuildRequest("/NAS/ftp", `{"type":"ftp","host":"nas","username":"admin","password":"secret:FTP"}`); err == nil {
+		t.Error("BuildRequest ignored a secret that failed to resolve")
+	}
+
+	p := NewProtocol(nil)
+	for _, value := range []string{
+		`{"type":"nfs","host":"nas"}`,
+		`{"type":"smb","host":"nas","username":"admin"}`,
+		`{"type":"ftp","host":"nas","username":"admin","root":"data"}`,
+	} {
+		if _, err := p.BuildRequest("/NAS/x", value); err == nil {
+			t.Errorf("BuildRequest(%s) succeeded, want error", value)
+		}
+	}
+}
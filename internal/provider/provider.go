@@ -1,7 +1,16 @@
 // Package provider 定义存储提供商接口
 package provider
 
-import "github.com/yzbtdiy/openlist_batch/internal/model"
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
 
 // Provider 存储提供商接口
 type Provider interface {
@@ -13,9 +22,120 @@ type Provider interface {
 	BuildRequest(mountPath string, shareURL string) (*model.StorageRequest, error)
 }
 
-// UpdateableProvider 支持更新的提供商接口
+// UpdateableProvider 支持更新凭据的提供商接口
 type UpdateableProvider interface {
 	Provider
-	// BuildUpdateRequest 构建更新请求
-	BuildUpdateRequest(item model.StorageItem, newToken string) (*model.StorageRequest, error)
+	// BuildUpdateRequest 用提供商当前的凭据构建已有存储的更新请求,
+	// 只替换凭据相关字段, changed 表示凭据是否有变化
+	BuildUpdateRequest(item model.StorageItem) (req *model.StorageUpdateRequest, changed bool, err error)
+}
+
+// MultiDriverProvider 按条目选择驱动的提供商接口, Driver 返回空字符串
+type MultiDriverProvider interface {
+	Provider
+	// Drivers 返回条目可能使用的所有驱动
+	Drivers() []string
+}
+
+// HasDriver 检查驱动是否属于提供商
+func HasDriver(p Provider, driver string) bool {
+	if m, ok := p.(MultiDriverProvider); ok {
+		return slices.Contains(m.Drivers(), driver)
+	}
+	return p.Driver() == driver
+}
+
+// buildCredentialUpdate 把凭据字段写入存储的附加信息并构建更新请求
+func buildCredentialUpdate(item model.StorageItem, credentials map[string]any) (*model.StorageUpdateRequest, bool, error) {
+	var addition map[string]any
+	if err := json.Unmarshal([]byte(item.Addition), &addition); err != nil {
+		return nil, false, fmt.Errorf("解析原有附加信息失败: %w", err)
+	}
+	if addition == nil {
+		addition = make(map[string]any)
+	}
+
+	changed := false
+	for k, v := range credentials {
+		if old, ok := addition[k]; !ok || old != v {
+			addition[k] = v
+			changed = true
+		}
+	}
+
+	data, err := json.Marshal(addition)
+	if err != nil {
+		return nil, false, fmt.Errorf("序列化附加信息失败: %w", err)
+	}
+	req := model.NewStorageUpdateRequest(item)
+	req.Addition = string(data)
+	return req, changed, nil
+}
+
+// FolderPathProvider 条目可以用路径指定网盘中文件夹的提供商接口
+//
+// 驱动只接受文件夹 ID, 路径由调用方连接 OpenList 解析: 以 FolderPath 返回的请求
+// 临时挂载网盘根目录, 按路径列出目录找到文件夹 ID, 再用 WithFolderID 换回条目
+type FolderPathProvider interface {
+	Provider
+	// FolderPath 返回条目中以路径指定的文件夹, 以及挂载同一网盘根目录的请求;
+	// 条目不是路径时 dir 为空
+	FolderPath(value string) (root *model.StorageRequest, dir string, err error)
+	// WithFolderID 返回把条目中的路径换成文件夹 ID 后的条目
+	WithFolderID(value, folderID string) (string, error)
+}
+
+// MountChecker 条目引用 OpenList 中已有存储的提供商接口
+type MountChecker interface {
+	// CheckMounts 检查条目引用的路径是否都位于 mounts 中的某个挂载路径下
+	CheckMounts(value string, mounts []string) error
+}
+
+// Validator 支持静态检查分享链接的提供商接口
+type Validator interface {
+	// Validate 不连接网络, 按提供商的规则检查分享链接, 返回发现的所有问题
+	Validate(shareURL string) error
+}
+
+// sharePwdLength 分享提取码长度
+const sharePwdLength = 4
+
+// checkShareHost 检查分享链接的协议和域名
+func checkShareHost(u *url.URL, hosts []string) error {
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("分享链接应以 https:// 开头")
+	}
+	if !slices.Contains(hosts, strings.ToLower(u.Hostname())) {
+		return fmt.Errorf("分享链接域名 %q 不正确, 应为 %s", u.Hostname(), strings.Join(hosts, ", "))
+	}
+	return nil
+}
+
+// checkSharePwd 检查提取码, 为空表示没有提取码
+func checkSharePwd(pwd string) error {
+	if pwd == "" {
+		return nil
+	}
+	if len(pwd) != sharePwdLength || strings.IndexFunc(pwd, func(r rune) bool {
+		return !('0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z')
+	}) >= 0 {
+		return fmt.Errorf("提取码 %q 应为 %d 位字母或数字", pwd, sharePwdLength)
+	}
+	return nil
+}
+
+// parseFolderPath 解析条目中以 / 开头的文件夹路径并规范化, 不是路径时返回空字符串
+func parseFolderPath(value string) (string, error) {
+	if !strings.HasPrefix(value, "/") {
+		return "", nil
+	}
+	if strings.Contains(value, "\\") {
+		return "", fmt.Errorf("文件夹路径 %q 应使用 / 分隔", value)
+	}
+	for _, part := range strings.Split(value, "/") {
+		if part == "." || part == ".." {
+			return "", fmt.Errorf("文件夹路径 %q 不能包含 . 或 ..", value)
+		}
+	}
+	return path.Clean(value), nil
 }
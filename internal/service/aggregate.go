@@ -0,0 +1,234 @@
+// Package service 提供核心业务逻辑
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+	"github.com/yzbtdiy/openlist_batch/internal/provider"
+)
+
+// 别名分组的状态
+const (
+	AliasNew      = "新建"
+	AliasPresent  = "已存在"
+	AliasConflict = "冲突"
+)
+
+// AliasGroup 名称相同、可以合并为一个别名的一组存储
+type AliasGroup struct {
+	Category  string // 别名所在的分类
+	Name      string // 别名名称
+	MountPath string // 按 mount_path 规则生成的别名挂载路径
+	Members   []model.StorageItem
+	Status    string // AliasNew, AliasPresent 或 AliasConflict
+	Reason    string // 冲突的原因
+}
+
+// Paths 返回组内存储的挂载路径
+func (g AliasGroup) Paths() []string {
+	paths := make([]string, 0, len(g.Members))
+	for _, item := range g.Members {
+		paths = append(paths, item.MountPath)
+	}
+	return paths
+}
+
+// PlanAliases 按规范化后的名称把存储分组, 返回可以合并为别名的分组, 按挂载路径排序
+//
+// 只考虑满足 filter 的、未禁用的非别名存储; 名称取挂载路径的最后一段, 删除 ignore
+// 匹配的部分后转为半角小写并去掉空白和标点再比较; 一组至少两个存储且来自
+// min_drivers 种驱动才会合并. 别名挂载路径上已有存储时, 包含相同路径的别名记为
+// 已存在, 其他情况记为冲突
+func (s *BatchService) PlanAliases(items []model.StorageItem, filter *Filter, rules config.AliasAggregate) ([]AliasGroup, error) {
+	ignore := make([]*regexp.Regexp, 0, len(rules.Ignore))
+	for _, expr := range rules.Ignore {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("无效的正则表达式 %s: %w", expr, err)
+		}
+		ignore = append(ignore, re)
+	}
+	alias := provider.NewAlias()
+
+	existing := make(map[string]model.StorageItem, len(items))
+	candidates := make([]model.StorageItem, 0, len(items))
+	for _, item := range items {
+		existing[item.MountPath] = item
+		if item.Driver == alias.Driver() || item.Disabled || item.MountPath == "/" || !filter.Match(item) {
+			continue
+		}
+		candidates = append(candidates, item)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].MountPath < candidates[j].MountPath })
+
+	// 按规范化名称分组, 保留第一个存储去掉 ignore 部分后的名称用于显示
+	groups := make(map[string][]model.StorageItem)
+	names := make(map[string]string)
+	var keys []string
+	for _, item := range candidates {
+		name := path.Base(item.MountPath)
+		for _, re := range ignore {
+			name = re.ReplaceAllString(name, "")
+		}
+		name = strings.TrimSpace(name)
+		key := normalizeName(name)
+		if key == "" {
+			continue
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+			names[key] = name
+		}
+		groups[key] = append(groups[key], item)
+	}
+
+	var plan []AliasGroup
+	planned := make(map[string]bool)
+	for _, key := range keys {
+		members := groups[key]
+		drivers := make(map[string]bool)
+		for _, item := range members {
+			drivers[item.Driver] = true
+		}
+		if len(members) < 2 || len(drivers) < rules.DriverCount() {
+			continue
+		}
+
+		first := members[0].MountPath
+		r := strings.NewReplacer(
+			"{name}", names[key],
+			"{category}", path.Base(path.Dir(first)),
+			"{count}", strconv.Itoa(len(members)),
+		)
+		group := AliasGroup{
+			Category: rules.AggregateCategory(),
+			Name:     r.Replace(rules.NameTemplate()),
+			Members:  members,
+			Status:   AliasNew,
+		}
+		value, err := json.Marshal(group.Paths())
+		if err != nil {
+			return nil, fmt.Errorf("序列化路径列表失败: %w", err)
+		}
+		group.MountPath, err = renderMountPath(s.cfg.MountPath, alias, group.Category, group.Name, string(value))
+		if err != nil {
+			log.Printf("%s: 别名名称 %q 无效, 跳过", first, group.Name)
+			continue
+		}
+		if planned[group.MountPath] {
+			log.Printf("%s: 别名路径 %s 与其他分组相同, 跳过", first, group.MountPath)
+			continue
+		}
+		planned[group.MountPath] = true
+
+		if item, ok := existing[group.MountPath]; ok {
+			switch {
+			case item.Driver != alias.Driver():
+				group.Status, group.Reason = AliasConflict, "路径已被 "+item.Driver+" 存储占用"
+			case sameAliasPaths(item, group.Paths()):
+				group.Status = AliasPresent
+			default:
+				group.Status, group.Reason = AliasConflict, "已有的别名包含不同的路径"
+			}
+		}
+		plan = append(plan, group)
+	}
+
+	sort.Slice(plan, func(i, j int) bool { return plan[i].MountPath < plan[j].MountPath })
+	return plan, nil
+}
+
+// CreateAliases 为状态为新建的分组创建别名存储
+func (s *BatchService) CreateAliases(groups []AliasGroup) Result {
+	var result Result
+	var created []string
+	alias := provider.NewAlias()
+	for _, g := range groups {
+		if g.Status != AliasNew {
+			result.Skipped++
+			continue
+		}
+
+		value, err := json.Marshal(g.Paths())
+		if err != nil {
+			log.Printf("[%s] %s 序列化路径列表失败: %v", alias.Name(), g.MountPath, err)
+			result.Failed++
+			continue
+		}
+		req, err := alias.BuildRequest(g.MountPath, string(value))
+		if err != nil {
+			log.Printf("[%s] %s 构建请求失败: %v", alias.Name(), g.MountPath, err)
+			result.Failed++
+			continue
+		}
+		if err := s.AddStorage(req); err != nil {
+			log.Printf("[%s] %s 添加失败: %v", alias.Name(), g.MountPath, err)
+			result.Failed++
+			continue
+		}
+		log.Printf("[%s] %s 添加成功", alias.Name(), g.MountPath)
+		result.Added++
+		created = append(created, g.MountPath)
+	}
+	result.Verify = s.VerifyMounts(created)
+	return result
+}
+
+// AliasListOf 把分组转为 alias.yaml 格式的挂载列表, 冲突的分组不包含在内
+func AliasListOf(groups []AliasGroup) config.AliasList {
+	list := make(config.AliasList)
+	for _, g := range groups {
+		if g.Status == AliasConflict {
+			continue
+		}
+		if list[g.Category] == nil {
+			list[g.Category] = make(map[string][]string)
+		}
+		list[g.Category][g.Name] = g.Paths()
+	}
+	return list
+}
+
+// sameAliasPaths 检查别名存储的 paths 是否与给定路径相同 (不计顺序和显示名称)
+func sameAliasPaths(item model.StorageItem, paths []string) bool {
+	var a model.AliasAddition
+	if err := json.Unmarshal([]byte(item.Addition), &a); err != nil {
+		return false
+	}
+	var have []string
+	for _, line := range strings.Split(a.Paths, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if i := strings.Index(line, ":/"); i >= 0 && !strings.HasPrefix(line, "/") {
+			line = line[i+1:]
+		}
+		have = append(have, line)
+	}
+	want := slices.Clone(paths)
+	slices.Sort(have)
+	slices.Sort(want)
+	return slices.Equal(have, want)
+}
+
+// normalizeName 返回比较用的名称: 转为半角小写, 去掉空白、标点和符号
+func normalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, toHalfWidth(name))
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"strconv"
 	"strings"
 	"sync"
 
@@ -22,13 +21,33 @@ const (
 	StorageCreateEndpoint = "/api/admin/storage/create"
 	StorageDeleteEndpoint = "/api/admin/storage/delete"
 	StorageUpdateEndpoint = "/api/admin/storage/update"
+	FsListEndpoint        = "/api/fs/list"
 )
 
+// Result 批量添加结果统计
+type Result struct {
+	Added    int
+	Replaced int // duplicate 为 replace 时删除的重复存储
+	Skipped  int // 挂载路径冲突而跳过
+	Failed   int
+	Verify   VerifyResult
+}
+
+// Merge 合并另一次操作的统计
+func (r *Result) Merge(other Result) {
+	r.Added += other.Added
+	r.Replaced += other.Replaced
+	r.Skipped += other.Skipped
+	r.Failed += other.Failed
+	r.Verify.Merge(other.Verify)
+}
+
 // BatchService 批处理服务
 type BatchService struct {
 	cfg    *config.Config
 	client *client.Client
 	loader *config.Loader
+	force  bool // 允许批量修改受保护的存储
 }
 
 // NewBatchService 创建批处理服务
@@ -40,6 +59,28 @@ func NewBatchService(cfg *config.Config, loader *config.Loader) *BatchService {
 	}
 }
 
+// SetForce 设置是否允许批量命令删除或覆盖 protected_paths 中的存储
+func (s *BatchService) SetForce(force bool) {
+	s.force = force
+}
+
+// IsProtected 检查存储是否受保护, 强制模式下始终返回 false
+func (s *BatchService) IsProtected(mountPath string) bool {
+	return !s.force && s.cfg.IsProtected(mountPath)
+}
+
+// SplitProtected 把存储分为可修改和受保护两部分
+func (s *BatchService) SplitProtected(items []model.StorageItem) (allowed, protected []model.StorageItem) {
+	for _, item := range items {
+		if s.IsProtected(item.MountPath) {
+			protected = append(protected, item)
+		} else {
+			allowed = append(allowed, item)
+		}
+	}
+	return allowed, protected
+}
+
 // ValidateToken 验证 token 是否有效
 func (s *BatchService) ValidateToken() bool {
 	resp, err := s.client.Get(StorageListEndpoint)
@@ -49,6 +90,27 @@ func (s *BatchService) ValidateToken() bool {
 	return resp.Code == 200
 }
 
+// UseConfigToken 缓存的 token 无效时改用 config.yaml 中的 token, 有效时写入缓存
+//
+// 配置中的 token 没有被缓存覆盖或同样无效时返回 false
+func (s *BatchService) UseConfigToken() bool {
+	token := s.cfg.ConfigToken()
+	if token == "" {
+		return false
+	}
+	s.client.SetToken(token)
+	if !s.ValidateToken() {
+		s.client.SetToken(s.cfg.Token)
+		return false
+	}
+
+	s.cfg.Token = token
+	if err := s.loader.SaveConfig(s.cfg); err != nil {
+		log.Printf("保存 token 失败: %v", err)
+	}
+	return true
+}
+
 // RefreshToken 刷新 token
 func (s *BatchService) RefreshToken() error {
 	authReq := model.AuthRequest{
@@ -147,8 +209,8 @@ func (s *BatchService) DeleteStorage(id int) error {
 	return nil
 }
 
-// UpdateStorage 更新存储
-func (s *BatchService) UpdateStorage(req *model.StorageRequest) error {
+// UpdateStorage 按 ID 更新存储
+func (s *BatchService) UpdateStorage(req *model.StorageUpdateRequest) error {
 	data, err := json.Marshal(req)
 	if err != nil {
 		return fmt.Errorf("序列化请求失败: %w", err)
@@ -166,164 +228,245 @@ func (s *BatchService) UpdateStorage(req *model.StorageRequest) error {
 	return nil
 }
 
-// BatchAddShares 批量添加分享链接
-func (s *BatchService) BatchAddShares(p provider.Provider, shares config.ShareList) {
-	var wg sync.WaitGroup
-
-	for category, shareMap := range shares {
-		for name, url := range shareMap {
-			wg.Add(1)
-			go func(category, name, url string) {
-				defer wg.Done()
-
-				mountPath := "/" + category + "/" + name
-				req, err := p.BuildRequest(mountPath, url)
-				if err != nil {
-					log.Printf("[%s] %s/%s 构建请求失败: %v", p.Name(), category, name, err)
-					return
-				}
-
-				if err := s.AddStorage(req); err != nil {
-					log.Printf("[%s] %s/%s 添加失败: %v", p.Name(), category, name, err)
-					return
-				}
-
-				log.Printf("[%s] %s/%s 添加成功", p.Name(), category, name)
-			}(category, name, url)
-		}
+// SaveStorage 按完整的存储信息更新存储, 可同时修改禁用状态;
+// 内容未变时提交相当于让 OpenList 重新加载该存储
+func (s *BatchService) SaveStorage(item model.StorageItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %w", err)
 	}
 
-	wg.Wait()
-}
+	resp, err := s.client.Post(StorageUpdateEndpoint, data)
+	if err != nil {
+		return err
+	}
 
-// BatchAddOneDriveApp 批量添加 OneDrive 应用
-func (s *BatchService) BatchAddOneDriveApp(p *provider.OneDriveApp, appList config.ShareList) {
-	var wg sync.WaitGroup
-
-	for category, appMap := range appList {
-		for name, emailInfo := range appMap {
-			wg.Add(1)
-			go func(category, name, emailInfo string) {
-				defer wg.Done()
-
-				mountPath := "/" + category + "/" + name
-				req, err := p.BuildRequest(mountPath, emailInfo)
-				if err != nil {
-					log.Printf("[OneDrive] %s/%s 构建请求失败: %v", category, name, err)
-					return
-				}
-
-				if err := s.AddStorage(req); err != nil {
-					log.Printf("[OneDrive] %s/%s 添加失败: %v", category, name, err)
-					return
-				}
-
-				log.Printf("[OneDrive] %s/%s 添加成功", category, name)
-			}(category, name, emailInfo)
-		}
+	if resp.Code != 200 {
+		return fmt.Errorf("%s", resp.Message)
 	}
-	wg.Wait()
+
+	return nil
 }
 
-// DeleteDisabledStorages 删除禁用的存储
-func (s *BatchService) DeleteDisabledStorages() error {
+// BatchAddShares 批量添加分享链接
+//
+// 挂载路径按 mount_path 规则生成, 已存在的路径视为冲突;
+// 与输入中前面的条目或已有存储是同一分享时按 duplicate 策略处理,
+// replace 策略下新存储添加成功后才删除被替换的存储
+func (s *BatchService) BatchAddShares(p provider.Provider, shares config.ShareList) (Result, error) {
 	list, err := s.GetStorageList()
 	if err != nil {
-		return err
+		return Result{}, fmt.Errorf("获取存储列表失败: %w", err)
 	}
-
+	existing := make(map[string]model.StorageItem, len(list.Content))
 	for _, item := range list.Content {
-		if item.Disabled {
-			if err := s.DeleteStorage(item.Id); err != nil {
-				log.Printf("删除存储 %d (%s) 失败: %v", item.Id, item.MountPath, err)
+		existing[item.MountPath] = item
+	}
+
+	shares = s.resolveFolderPaths(p, shares)
+	plan, err := s.PlanMounts(p, shares, existing)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		result  = Result{Skipped: plan.Skipped, Failed: plan.Invalid}
+		created []string
+	)
+	record := func(mountPath string, ok bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ok {
+			result.Added++
+			created = append(created, mountPath)
+		} else {
+			result.Failed++
+		}
+	}
+
+	// 先构建全部请求并去重, 再并发添加
+	type pendingAdd struct {
+		label   string
+		req     *model.StorageRequest
+		replace *model.StorageItem // replace 策略下添加成功后删除的重复存储
+	}
+	dups := s.newDuplicateChecker(list.Content)
+	for _, entry := range plan.Present {
+		label := "[" + p.Name() + "] " + entry.Label()
+		log.Printf("%s 已存在于 %s, 跳过", label, entry.MountPath)
+		result.Skipped++
+		if req, err := p.BuildRequest(entry.MountPath, entry.Value); err == nil {
+			dups.markSeen(label, req)
+		}
+	}
+	var pending []pendingAdd
+	for _, entry := range plan.Entries {
+		label := "[" + p.Name() + "] " + entry.Label()
+		req, err := p.BuildRequest(entry.MountPath, entry.Value)
+		if err != nil {
+			log.Printf("%s 构建请求失败: %v", label, err)
+			result.Failed++
+			continue
+		}
+		if err := checkMounts(p, entry.Value, list.Content); err != nil {
+			log.Printf("%s 引用的存储不存在: %v", label, err)
+			result.Failed++
+			continue
+		}
+
+		add, replace := dups.check(label, req)
+		if !add || (replace != nil && !s.canReplace(replace)) {
+			result.Skipped++
+			continue
+		}
+		pending = append(pending, pendingAdd{label, req, replace})
+	}
+
+	for _, add := range pending {
+		wg.Add(1)
+		go func(add pendingAdd) {
+			defer wg.Done()
+
+			if err := s.AddStorage(add.req); err != nil {
+				log.Printf("%s 添加失败: %v", add.label, err)
+				record(add.req.MountPath, false)
+				return
+			}
+
+			log.Printf("%s 添加成功", add.label)
+			record(add.req.MountPath, true)
+			if add.replace == nil {
+				return
+			}
+			replaced := s.replaceDuplicate(add.replace)
+			mu.Lock()
+			defer mu.Unlock()
+			if replaced {
+				result.Replaced++
 			} else {
-				log.Printf("已删除存储 %d (%s)", item.Id, item.MountPath)
+				result.Failed++
 			}
-		}
+		}(add)
 	}
 
-	return nil
+	wg.Wait()
+	result.Verify = s.VerifyMounts(created)
+	return result, nil
 }
 
-// DeleteAllStorages 删除所有存储
-func (s *BatchService) DeleteAllStorages() error {
-	list, err := s.GetStorageList()
-	if err != nil {
-		return err
+// checkMounts 检查条目引用的路径是否在已有存储下, 提供商不引用其他存储时不检查
+func checkMounts(p provider.Provider, value string, items []model.StorageItem) error {
+	checker, ok := p.(provider.MountChecker)
+	if !ok {
+		return nil
 	}
+	mounts := make([]string, 0, len(items))
+	for _, item := range items {
+		mounts = append(mounts, item.MountPath)
+	}
+	return checker.CheckMounts(value, mounts)
+}
 
-	for _, item := range list.Content {
+// DeleteStorages 批量删除存储, 受保护的存储跳过, 返回删除和失败的数量
+func (s *BatchService) DeleteStorages(items []model.StorageItem) (deleted, failed int) {
+	for _, item := range items {
+		if s.IsProtected(item.MountPath) {
+			log.Printf("跳过受保护的存储 %d (%s)", item.Id, item.MountPath)
+			continue
+		}
 		if err := s.DeleteStorage(item.Id); err != nil {
 			log.Printf("删除存储 %d (%s) 失败: %v", item.Id, item.MountPath, err)
-		} else {
-			log.Printf("已删除存储 %d (%s)", item.Id, item.MountPath)
+			failed++
+			continue
 		}
+		log.Printf("已删除存储 %d (%s)", item.Id, item.MountPath)
+		deleted++
 	}
+	return deleted, failed
+}
 
-	return nil
+// ReadRotatedRefreshToken 从指定存储的附加信息读取 OpenList 轮换后的 refresh_token
+//
+// ref 为存储 ID 或挂载路径; 存储状态不是 work 时其中的 token 可能已失效, 返回错误
+func (s *BatchService) ReadRotatedRefreshToken(ref string) (string, *model.StorageItem, error) {
+	item, err := s.FindStorage(ref)
+	if err != nil {
+		return "", nil, err
+	}
+	if item.Status != "work" {
+		return "", item, fmt.Errorf("存储 %d (%s) 状态为 %s, 其中的 refresh_token 可能已失效", item.Id, item.MountPath, item.Status)
+	}
+
+	addition, err := DecodeAddition(item.Addition)
+	if err != nil {
+		return "", item, err
+	}
+	token, _ := addition["refresh_token"].(string)
+	if token == "" {
+		return "", item, fmt.Errorf("存储 %d (%s) 中没有 refresh_token", item.Id, item.MountPath)
+	}
+	return token, item, nil
+}
+
+// RotateResult 凭据更新结果统计
+type RotateResult struct {
+	Updated   int
+	Unchanged int
+	Skipped   int // 受保护而跳过
+	Failed    int
+}
+
+// Merge 合并另一次更新的统计
+func (r *RotateResult) Merge(other RotateResult) {
+	r.Updated += other.Updated
+	r.Unchanged += other.Unchanged
+	r.Skipped += other.Skipped
+	r.Failed += other.Failed
 }
 
-// UpdateAliyunRefreshToken 更新阿里云盘 RefreshToken
-func (s *BatchService) UpdateAliyunRefreshToken(newToken string) error {
+// RotateCredentials 用提供商当前的凭据更新该驱动的所有存储
+//
+// 凭据没有变化且状态正常的存储不会重复提交, 受保护的存储跳过
+func (s *BatchService) RotateCredentials(p provider.UpdateableProvider) (RotateResult, error) {
+	var result RotateResult
 	list, err := s.GetStorageList()
 	if err != nil {
-		return err
+		return result, fmt.Errorf("获取存储列表失败: %w", err)
 	}
 
-	aliyunProvider := provider.NewAliyunShare(newToken)
-
 	for _, item := range list.Content {
-		if item.Driver != aliyunProvider.Driver() {
+		if item.Driver != p.Driver() {
 			continue
 		}
-
-		req, err := aliyunProvider.BuildUpdateRequest(item, newToken)
-		if err != nil {
-			log.Printf("构建更新请求失败 (%s): %v", item.MountPath, err)
+		if s.IsProtected(item.MountPath) {
+			log.Printf("跳过受保护的存储 %s", item.MountPath)
+			result.Skipped++
 			continue
 		}
 
-		// 需要设置 ID 用于更新
-		data, _ := json.Marshal(req)
-		var reqMap map[string]any
-		json.Unmarshal(data, &reqMap)
-		reqMap["id"] = item.Id
-		reqMap["status"] = "work"
-
-		updateData, _ := json.Marshal(reqMap)
-		resp, err := s.client.Post(StorageUpdateEndpoint, updateData)
+		req, changed, err := p.BuildUpdateRequest(item)
 		if err != nil {
-			log.Printf("更新存储失败 (%s): %v", item.MountPath, err)
+			log.Printf("构建更新请求失败 (%s): %v", item.MountPath, err)
+			result.Failed++
 			continue
 		}
-
-		if resp.Code == 200 {
-			log.Printf("已更新 %s", item.MountPath)
-		} else {
-			log.Printf("更新失败 (%s): %s", item.MountPath, resp.Message)
-		}
-	}
-
-	return nil
-}
-
-// DeleteStorageByID 根据 ID 删除存储
-func (s *BatchService) DeleteStorageByID(ids []string) error {
-	for _, idStr := range ids {
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			log.Printf("无效的存储 ID: %s", idStr)
+		if !changed && item.Status == "work" {
+			result.Unchanged++
 			continue
 		}
 
-		if err := s.DeleteStorage(id); err != nil {
-			log.Printf("删除存储 %d 失败: %v", id, err)
-		} else {
-			log.Printf("已删除存储 %d", id)
+		if err := s.UpdateStorage(req); err != nil {
+			log.Printf("更新失败 (%s): %v", item.MountPath, err)
+			result.Failed++
+			continue
 		}
+		log.Printf("已更新 %s", item.MountPath)
+		result.Updated++
 	}
 
-	return nil
+	return result, nil
 }
 
 // ExportPikPakShare 导出 PikPakShare 存储到 ShareList 格式
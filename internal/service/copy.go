@@ -0,0 +1,223 @@
+// Package service 提供核心业务逻辑
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
+
+// CopyResult 复制存储结果统计
+type CopyResult struct {
+	Created int
+	Updated int
+	Skipped int
+	Failed  int
+}
+
+// CopyFrom 从源实例复制存储到当前实例
+//
+// 挂载路径已存在时更新, 否则创建; 驱动过滤、路径改写与凭据替换按 copy 配置执行
+func (s *BatchService) CopyFrom(src *BatchService) (CopyResult, error) {
+	var result CopyResult
+
+	srcList, err := src.GetStorageList()
+	if err != nil {
+		return result, fmt.Errorf("获取源实例存储列表失败: %w", err)
+	}
+
+	dstList, err := s.GetStorageList()
+	if err != nil {
+		return result, fmt.Errorf("获取目标实例存储列表失败: %w", err)
+	}
+
+	existing := make(map[string]int, len(dstList.Content))
+	for _, item := range dstList.Content {
+		existing[item.MountPath] = item.Id
+	}
+
+	opts := s.cfg.Copy
+	for _, item := range srcList.Content {
+		if !driverAllowed(opts, item.Driver) {
+			result.Skipped++
+			continue
+		}
+		if item.Disabled && !opts.IncludeDisabled {
+			log.Printf("跳过已禁用的存储 %s", item.MountPath)
+			result.Skipped++
+			continue
+		}
+
+		item.MountPath = rewritePath(opts.PathRewrite, item.MountPath)
+		req := model.NewStorageRequest(item)
+
+		addition, err := s.substituteCredentials(item.Driver, item.Addition)
+		if err != nil {
+			log.Printf("替换凭据失败 (%s): %v", item.MountPath, err)
+			result.Failed++
+			continue
+		}
+		req.Addition = addition
+
+		if id, ok := existing[item.MountPath]; ok {
+			update := model.NewStorageUpdateRequest(item)
+			update.Id = id
+			update.StorageRequest = req
+			if s.IsProtected(item.MountPath) {
+				log.Printf("跳过受保护的存储 %s", item.MountPath)
+				result.Skipped++
+				continue
+			}
+			if err := s.UpdateStorage(update); err != nil {
+				log.Printf("更新失败 (%s): %v", item.MountPath, err)
+				result.Failed++
+				continue
+			}
+			log.Printf("已更新 %s", item.MountPath)
+			result.Updated++
+			continue
+		}
+
+		if err := s.AddStorage(&req); err != nil {
+			log.Printf("创建失败 (%s): %v", item.MountPath, err)
+			result.Failed++
+			continue
+		}
+		log.Printf("已创建 %s", item.MountPath)
+		result.Created++
+	}
+
+	return result, nil
+}
+
+// driverAllowed 检查驱动是否在允许复制的范围内
+func driverAllowed(opts config.Copy, driver string) bool {
+	if len(opts.DriversAllow) > 0 && !slices.Contains(opts.DriversAllow, driver) {
+		return false
+	}
+	return !slices.Contains(opts.DriversDeny, driver)
+}
+
+// rewritePath 按第一条匹配的规则改写挂载路径前缀
+func rewritePath(rules []config.PathRewrite, mountPath string) string {
+	for _, r := range rules {
+		from := strings.TrimSuffix(r.From, "/")
+		if mountPath != from && !strings.HasPrefix(mountPath, from+"/") {
+			continue
+		}
+		to := strings.TrimSuffix(r.To, "/")
+		rewritten := to + strings.TrimPrefix(mountPath, from)
+		if rewritten == "" {
+			return "/"
+		}
+		return rewritten
+	}
+	return mountPath
+}
+
+// substituteCredentials 用当前实例的凭据替换附加信息中的凭据
+//
+// 只使用当前实例已启用的配置中填写了的值 (非空且不是模板占位值),
+// 其余字段保留源实例的值
+func (s *BatchService) substituteCredentials(driver, addition string) (string, error) {
+	switch driver {
+	case "AliyundriveShare":
+		c := s.cfg.AliyunShare
+		if !c.Enable || config.IsUnset(c.RefreshToken) {
+			return addition, nil
+		}
+		var a model.AliyunShareAddition
+		if err := json.Unmarshal([]byte(addition), &a); err != nil {
+			return "", err
+		}
+		a.RefreshToken = c.RefreshToken
+		return marshalAddition(a)
+
+	case "AliyundriveOpen":
+		c := s.cfg.AliyunOpen
+		if !c.Enable || config.IsUnset(c.RefreshToken) {
+			return addition, nil
+		}
+		var a model.AliyunOpenAddition
+		if err := json.Unmarshal([]byte(addition), &a); err != nil {
+			return "", err
+		}
+		a.RefreshToken = c.RefreshToken
+		// 当前实例使用自己的应用时才替换应用凭据, 否则保留源实例的刷新方式
+		if !config.IsUnset(c.ClientID) {
+			a.UseOnlineAPI = false
+			a.ClientId = c.ClientID
+			a.ClientSecret = c.ClientSecret
+		}
+		return marshalAddition(a)
+
+	case "PikPakShare":
+		c := s.cfg.PikPakShare
+		if !c.Enable {
+			return addition, nil
+		}
+		var a model.PikPakShareAddition
+		if err := json.Unmarshal([]byte(addition), &a); err != nil {
+			return "", err
+		}
+		a.Platform = config.OrDefault(c.Platform, a.Platform)
+		a.DeviceId = config.OrDefault(c.DeviceID, a.DeviceId)
+		return marshalAddition(a)
+
+	case "PikPak":
+		if !s.cfg.PikPak.Enable {
+			return addition, nil
+		}
+		var a model.PikPakAddition
+		if err := json.Unmarshal([]byte(addition), &a); err != nil {
+			return "", err
+		}
+		for _, account := range s.cfg.PikPak.AccountList() {
+			if account.Username != a.Username {
+				continue
+			}
+			if !config.IsUnset(account.Password) {
+				a.Password = account.Password
+			}
+			a.RefreshToken = config.OrDefault(account.RefreshToken, a.RefreshToken)
+			a.Platform = config.OrDefault(account.Platform, a.Platform)
+			a.DeviceId = config.OrDefault(account.DeviceID, a.DeviceId)
+			return marshalAddition(a)
+		}
+		return addition, nil
+
+	case "OnedriveAPP":
+		if !s.cfg.OneDriveApp.Enable {
+			return addition, nil
+		}
+		var a model.OneDriveAppAddition
+		if err := json.Unmarshal([]byte(addition), &a); err != nil {
+			return "", err
+		}
+		for _, t := range s.cfg.OneDriveApp.Tenants {
+			if t.TenantID != a.TenantId || config.IsUnset(t.ClientID) || config.IsUnset(t.ClientSecret) {
+				continue
+			}
+			a.ClientId = t.ClientID
+			a.ClientSecret = t.ClientSecret
+			return marshalAddition(a)
+		}
+		return addition, nil
+	}
+
+	return addition, nil
+}
+
+// marshalAddition 序列化附加信息
+func marshalAddition(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("序列化附加信息失败: %w", err)
+	}
+	return string(data), nil
+}
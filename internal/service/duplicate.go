@@ -0,0 +1,117 @@
+// Package service 提供核心业务逻辑
+package service
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
+
+// shareKey 识别同一分享的键
+type shareKey struct {
+	driver       string
+	shareID      string
+	rootFolderID string
+}
+
+// shareKeyOf 从附加信息中提取分享键, 没有 share_id 的存储 (如 OneDrive) 不参与去重
+func shareKeyOf(driver, addition string) (shareKey, bool) {
+	var a struct {
+		ShareID      string `json:"share_id"`
+		RootFolderID string `json:"root_folder_id"`
+	}
+	if err := json.Unmarshal([]byte(addition), &a); err != nil || a.ShareID == "" {
+		return shareKey{}, false
+	}
+	return shareKey{driver: driver, shareID: a.ShareID, rootFolderID: a.RootFolderID}, true
+}
+
+// duplicateChecker 检查待添加的分享是否与输入中前面的条目或服务器上已有的存储重复
+type duplicateChecker struct {
+	policy   string
+	seen     map[shareKey]string // 输入中已出现的分享, 值为条目名称
+	existing map[shareKey]model.StorageItem
+}
+
+// newDuplicateChecker 按服务器上已有的存储创建重复检查器
+func (s *BatchService) newDuplicateChecker(items []model.StorageItem) *duplicateChecker {
+	d := &duplicateChecker{
+		policy:   s.cfg.DuplicatePolicy(),
+		seen:     make(map[shareKey]string),
+		existing: make(map[shareKey]model.StorageItem),
+	}
+	for _, item := range items {
+		if key, ok := shareKeyOf(item.Driver, item.Addition); ok {
+			if _, dup := d.existing[key]; !dup {
+				d.existing[key] = item
+			}
+		}
+	}
+	return d
+}
+
+// markSeen 记录输入中已处理的分享, 用于已存在而不需要添加的条目
+func (d *duplicateChecker) markSeen(label string, req *model.StorageRequest) {
+	if key, ok := shareKeyOf(req.Driver, req.Addition); ok {
+		if _, dup := d.seen[key]; !dup {
+			d.seen[key] = label
+		}
+	}
+}
+
+// check 检查分享是否重复, 返回是否继续添加, 以及 replace 策略下需要先删除的存储
+//
+// 输入中的重复只保留第一条 (report 策略除外); 与服务器上其他路径的存储重复时,
+// skip 跳过, report 记录后仍然添加, replace 删除已有存储后添加
+func (d *duplicateChecker) check(label string, req *model.StorageRequest) (bool, *model.StorageItem) {
+	key, ok := shareKeyOf(req.Driver, req.Addition)
+	if !ok {
+		return true, nil
+	}
+
+	if first, dup := d.seen[key]; dup {
+		if d.policy == config.DuplicateReport {
+			log.Printf("%s 与 %s 是同一分享, 仍然添加", label, first)
+			return true, nil
+		}
+		log.Printf("%s 与 %s 是同一分享, 跳过", label, first)
+		return false, nil
+	}
+	d.seen[key] = label
+
+	item, dup := d.existing[key]
+	if !dup || item.MountPath == req.MountPath {
+		return true, nil
+	}
+	switch d.policy {
+	case config.DuplicateReport:
+		log.Printf("%s 与已有存储 %d (%s) 是同一分享, 仍然添加", label, item.Id, item.MountPath)
+		return true, nil
+	case config.DuplicateReplace:
+		log.Printf("%s 与已有存储 %d (%s) 是同一分享, 将替换", label, item.Id, item.MountPath)
+		return true, &item
+	}
+	log.Printf("%s 与已有存储 %d (%s) 是同一分享, 跳过", label, item.Id, item.MountPath)
+	return false, nil
+}
+
+// canReplace 检查重复的存储能否被替换, 受保护时返回 false
+func (s *BatchService) canReplace(item *model.StorageItem) bool {
+	if s.IsProtected(item.MountPath) {
+		log.Printf("存储 %d (%s) 受保护, 不替换", item.Id, item.MountPath)
+		return false
+	}
+	return true
+}
+
+// replaceDuplicate 在新存储添加成功后删除被替换的重复存储, 删除失败时返回 false
+func (s *BatchService) replaceDuplicate(item *model.StorageItem) bool {
+	if err := s.DeleteStorage(item.Id); err != nil {
+		log.Printf("删除重复的存储 %d (%s) 失败: %v", item.Id, item.MountPath, err)
+		return false
+	}
+	log.Printf("已删除重复的存储 %d (%s)", item.Id, item.MountPath)
+	return true
+}
@@ -0,0 +1,169 @@
+// Package service 提供核心业务逻辑
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
+
+// EditableFields 可编辑的通用字段, 附加信息用 addition.<键> 编辑
+var EditableFields = []string{"path", "order", "remark", "cache", "disabled", "webdav"}
+
+// webdavPolicies OpenList 支持的 WebDAV 策略
+var webdavPolicies = []string{"302_redirect", "use_proxy_url", "native_proxy"}
+
+// FieldChange 存储字段的变更
+type FieldChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// SetStorageField 修改存储的字段, 附加信息中的值保持原有类型
+func SetStorageField(item *model.StorageItem, field, value string) error {
+	if key, ok := strings.CutPrefix(field, "addition."); ok {
+		return setAdditionField(item, key, value)
+	}
+
+	switch strings.ToLower(field) {
+	case "path", "mount_path":
+		if !strings.HasPrefix(value, "/") {
+			return fmt.Errorf("挂载路径必须以 / 开头: %s", value)
+		}
+		item.MountPath = value
+	case "order":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("order 必须是数字: %s", value)
+		}
+		item.Order = n
+	case "remark":
+		item.Remark = value
+	case "cache", "cache_expiration":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("cache 必须是非负整数: %s", value)
+		}
+		item.CacheExpiration = n
+	case "disabled":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("disabled 必须是 true 或 false: %s", value)
+		}
+		item.Disabled = b
+	case "webdav", "webdav_policy":
+		if !containsFold(webdavPolicies, value) {
+			return fmt.Errorf("未知的 WebDAV 策略: %s, 可选值: %s", value, strings.Join(webdavPolicies, ", "))
+		}
+		item.WebdavPolicy = strings.ToLower(value)
+	default:
+		return fmt.Errorf("不支持编辑的字段: %s, 可选值: %s, addition.<键>", field, strings.Join(EditableFields, ", "))
+	}
+	return nil
+}
+
+// setAdditionField 修改附加信息中的键
+func setAdditionField(item *model.StorageItem, key, value string) error {
+	addition, err := DecodeAddition(item.Addition)
+	if err != nil {
+		return err
+	}
+
+	switch addition[key].(type) {
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s 必须是 true 或 false: %s", key, value)
+		}
+		addition[key] = b
+	case float64:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s 必须是数字: %s", key, value)
+		}
+		addition[key] = n
+	default:
+		addition[key] = value
+	}
+
+	item.Addition, err = marshalAddition(addition)
+	return err
+}
+
+// diffFields 参与比较的通用字段
+var diffFields = []struct {
+	name  string
+	value func(model.StorageItem) string
+}{
+	{"path", func(i model.StorageItem) string { return i.MountPath }},
+	{"order", func(i model.StorageItem) string { return strconv.Itoa(i.Order) }},
+	{"remark", func(i model.StorageItem) string { return i.Remark }},
+	{"cache", func(i model.StorageItem) string { return strconv.Itoa(i.CacheExpiration) }},
+	{"disabled", func(i model.StorageItem) string { return strconv.FormatBool(i.Disabled) }},
+	{"webdav", func(i model.StorageItem) string { return i.WebdavPolicy }},
+}
+
+// DiffStorage 比较存储修改前后的字段, 敏感的附加信息打码
+func DiffStorage(before, after model.StorageItem) []FieldChange {
+	var changes []FieldChange
+	for _, f := range diffFields {
+		if b, a := f.value(before), f.value(after); b != a {
+			changes = append(changes, FieldChange{Field: f.name, Before: b, After: a})
+		}
+	}
+
+	if before.Addition == after.Addition {
+		return changes
+	}
+	oldAddition, err1 := DecodeAddition(before.Addition)
+	newAddition, err2 := DecodeAddition(after.Addition)
+	if err1 != nil || err2 != nil {
+		return append(changes, FieldChange{Field: "addition", Before: before.Addition, After: after.Addition})
+	}
+
+	keys := make(map[string]bool)
+	for k := range oldAddition {
+		keys[k] = true
+	}
+	for k := range newAddition {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	for _, k := range sorted {
+		b, a := additionString(oldAddition, k), additionString(newAddition, k)
+		if b == a {
+			continue
+		}
+		if IsSecretKey(k) {
+			b, a = maskNonEmpty(b), maskNonEmpty(a)
+		}
+		changes = append(changes, FieldChange{Field: "addition." + k, Before: b, After: a})
+	}
+	return changes
+}
+
+// additionString 返回附加信息中键的字符串形式, 不存在时为空
+func additionString(addition map[string]any, key string) string {
+	v, ok := addition[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// maskNonEmpty 打码非空值, 空值保持为空以便看出增删
+func maskNonEmpty(s string) string {
+	if s == "" {
+		return ""
+	}
+	return MaskValue(s)
+}
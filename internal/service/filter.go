@@ -0,0 +1,232 @@
+// Package service 提供核心业务逻辑
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
+
+// Filter 存储筛选条件, 所有条件同时满足才匹配
+//
+// 语法: 以空格分隔的若干条件, 每个条件为 字段 运算符 值
+//
+//	字段:   id, driver, path, status, disabled, remark, order
+//	运算符: =  等于      != 不等于
+//	        ~  通配匹配  !~ 通配不匹配 (* 匹配任意字符, ? 匹配单个字符)
+//	        >  >= < <=   数值比较 (id, order)
+//	值:     多个候选用 | 分隔, 含空格时用双引号包裹
+//
+// 示例: driver=PikPakShare|AliyundriveShare path~/电影/* disabled=false
+type Filter struct {
+	conds []condition
+}
+
+type condition struct {
+	field  string
+	op     string
+	values []string
+	globs  []*regexp.Regexp
+}
+
+// filterFields 支持的筛选字段
+var filterFields = map[string]func(model.StorageItem) string{
+	"id":       func(i model.StorageItem) string { return strconv.Itoa(i.Id) },
+	"driver":   func(i model.StorageItem) string { return i.Driver },
+	"path":     func(i model.StorageItem) string { return i.MountPath },
+	"status":   func(i model.StorageItem) string { return i.Status },
+	"disabled": func(i model.StorageItem) string { return strconv.FormatBool(i.Disabled) },
+	"remark":   func(i model.StorageItem) string { return i.Remark },
+	"order":    func(i model.StorageItem) string { return strconv.Itoa(i.Order) },
+}
+
+// filterOps 运算符, 长的在前以便优先匹配
+var filterOps = []string{"!=", "!~", ">=", "<=", "=", "~", ">", "<"}
+
+// ParseFilter 解析筛选表达式, 空表达式匹配所有存储
+func ParseFilter(expr string) (*Filter, error) {
+	terms, err := splitTerms(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Filter{}
+	for _, term := range terms {
+		cond, err := parseCondition(term)
+		if err != nil {
+			return nil, err
+		}
+		f.conds = append(f.conds, cond)
+	}
+	return f, nil
+}
+
+// Match 检查存储是否满足所有条件
+func (f *Filter) Match(item model.StorageItem) bool {
+	if f == nil {
+		return true
+	}
+	for _, c := range f.conds {
+		if !c.match(filterFields[c.field](item)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply 返回满足条件的存储
+func (f *Filter) Apply(items []model.StorageItem) []model.StorageItem {
+	result := make([]model.StorageItem, 0, len(items))
+	for _, item := range items {
+		if f.Match(item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// splitTerms 按空格切分条件, 双引号内的空格保留
+func splitTerms(expr string) ([]string, error) {
+	var (
+		terms   []string
+		current strings.Builder
+		quoted  bool
+	)
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			if current.Len() > 0 {
+				terms = append(terms, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("筛选表达式中的引号未闭合")
+	}
+	if current.Len() > 0 {
+		terms = append(terms, current.String())
+	}
+	return terms, nil
+}
+
+// parseCondition 解析单个条件
+func parseCondition(term string) (condition, error) {
+	i := strings.IndexAny(term, "!=~<>")
+	if i <= 0 {
+		return condition{}, fmt.Errorf("无效的筛选条件: %s", term)
+	}
+	op := ""
+	for _, candidate := range filterOps {
+		if strings.HasPrefix(term[i:], candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return condition{}, fmt.Errorf("无效的筛选条件: %s", term)
+	}
+
+	field := strings.ToLower(term[:i])
+	if field == "mount_path" {
+		field = "path"
+	}
+	if _, ok := filterFields[field]; !ok {
+		return condition{}, fmt.Errorf("未知的筛选字段: %s", field)
+	}
+
+	c := condition{field: field, op: op, values: strings.Split(term[i+len(op):], "|")}
+	switch op {
+	case "~", "!~":
+		for _, v := range c.values {
+			re, err := globRegexp(v)
+			if err != nil {
+				return condition{}, err
+			}
+			c.globs = append(c.globs, re)
+		}
+	case ">", ">=", "<", "<=":
+		if field != "id" && field != "order" {
+			return condition{}, fmt.Errorf("字段 %s 不支持 %s 比较", field, op)
+		}
+		if len(c.values) != 1 {
+			return condition{}, fmt.Errorf("%s 比较只能有一个值", op)
+		}
+		if _, err := strconv.Atoi(c.values[0]); err != nil {
+			return condition{}, fmt.Errorf("%s 不是数字", c.values[0])
+		}
+	}
+	return c, nil
+}
+
+// match 检查字段值是否满足条件
+func (c condition) match(value string) bool {
+	switch c.op {
+	case "=":
+		return containsFold(c.values, value)
+	case "!=":
+		return !containsFold(c.values, value)
+	case "~", "!~":
+		matched := false
+		for _, re := range c.globs {
+			if re.MatchString(value) {
+				matched = true
+				break
+			}
+		}
+		return matched == (c.op == "~")
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return false
+	}
+	want, _ := strconv.Atoi(c.values[0])
+	switch c.op {
+	case ">":
+		return n > want
+	case ">=":
+		return n >= want
+	case "<":
+		return n < want
+	case "<=":
+		return n <= want
+	}
+	return false
+}
+
+// containsFold 不区分大小写检查值是否在候选中
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// globRegexp 把通配符转换为正则, * 可以跨越 /
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,101 @@
+// Package service 提供核心业务逻辑
+package service
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"time"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/provider"
+)
+
+// folderMountRoot 解析文件夹路径时临时存储的挂载目录
+const folderMountRoot = "/.openlist_batch"
+
+// resolveFolderPaths 把条目中以路径指定的文件夹换成文件夹 ID, 返回新的分享列表
+//
+// 每个网盘根目录临时添加一个存储, 列出路径的上级目录找到文件夹 ID, 完成后删除
+// 临时存储; 解析失败的条目保持不变, 随后构建请求时记为失败
+func (s *BatchService) resolveFolderPaths(p provider.Provider, shares config.ShareList) config.ShareList {
+	fp, ok := p.(provider.FolderPathProvider)
+	if !ok {
+		return shares
+	}
+
+	roots := make(map[string]string) // 根目录请求的附加信息 -> 临时挂载路径
+	rootErrs := make(map[string]error)
+	var temps []string
+	defer func() {
+		for _, mountPath := range temps {
+			item, err := s.FindStorage(mountPath)
+			if err == nil {
+				err = s.DeleteStorage(item.Id)
+			}
+			if err != nil {
+				log.Printf("[%s] 删除临时存储 %s 失败: %v", p.Name(), mountPath, err)
+			}
+		}
+	}()
+
+	resolved := make(config.ShareList, len(shares))
+	for _, category := range sortedKeys(shares) {
+		resolved[category] = make(map[string]string, len(shares[category]))
+		for _, name := range sortedKeys(shares[category]) {
+			value := shares[category][name]
+			resolved[category][name] = value
+
+			root, dir, err := fp.FolderPath(value)
+			if err != nil || dir == "" {
+				continue
+			}
+			label := "[" + p.Name() + "] " + category + "/" + name
+
+			key := root.Driver + root.Addition
+			if _, ok := roots[key]; !ok && rootErrs[key] == nil {
+				root.MountPath = fmt.Sprintf("%s/%s-%d", folderMountRoot, root.Driver, time.Now().UnixNano())
+				if err := s.AddStorage(root); err != nil {
+					rootErrs[key] = fmt.Errorf("临时挂载网盘根目录失败: %w", err)
+				} else {
+					roots[key] = root.MountPath
+					temps = append(temps, root.MountPath)
+				}
+			}
+			if err := rootErrs[key]; err != nil {
+				log.Printf("%s 解析文件夹路径 %s 失败: %v", label, dir, err)
+				continue
+			}
+
+			folderID, err := s.findFolderID(roots[key], dir)
+			if err == nil {
+				value, err = fp.WithFolderID(value, folderID)
+			}
+			if err != nil {
+				log.Printf("%s 解析文件夹路径 %s 失败: %v", label, dir, err)
+				continue
+			}
+			resolved[category][name] = value
+		}
+	}
+	return resolved
+}
+
+// findFolderID 在挂载于 mountPath 的网盘中按路径查找文件夹 ID
+func (s *BatchService) findFolderID(mountPath, dir string) (string, error) {
+	list, err := s.ListDir(path.Join(mountPath, path.Dir(dir)), true)
+	if err != nil {
+		return "", fmt.Errorf("列出目录 %s 失败: %w", path.Dir(dir), err)
+	}
+	name := path.Base(dir)
+	for _, obj := range list.Content {
+		if obj.Name != name || !obj.IsDir {
+			continue
+		}
+		if obj.ID == "" {
+			return "", fmt.Errorf("OpenList 没有返回文件夹 %s 的 ID", dir)
+		}
+		return obj.ID, nil
+	}
+	return "", fmt.Errorf("文件夹 %s 不存在", dir)
+}
@@ -0,0 +1,75 @@
+// Package service 提供核心业务逻辑
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
+
+// secretKeyParts 附加信息中视为敏感字段的键名片段
+var secretKeyParts = []string{"token", "secret", "password", "passwd", "pwd", "cookie", "private_key", "passphrase"}
+
+// FindStorage 按 ID 或挂载路径查找存储
+func (s *BatchService) FindStorage(ref string) (*model.StorageItem, error) {
+	list, err := s.GetStorageList()
+	if err != nil {
+		return nil, fmt.Errorf("获取存储列表失败: %w", err)
+	}
+
+	id, idErr := strconv.Atoi(ref)
+	for _, item := range list.Content {
+		if (idErr == nil && item.Id == id) || item.MountPath == ref {
+			return &item, nil
+		}
+	}
+	return nil, fmt.Errorf("未找到存储: %s", ref)
+}
+
+// DecodeAddition 解析存储的附加信息
+func DecodeAddition(addition string) (map[string]any, error) {
+	result := make(map[string]any)
+	if addition == "" {
+		return result, nil
+	}
+	if err := json.Unmarshal([]byte(addition), &result); err != nil {
+		return nil, fmt.Errorf("解析附加信息失败: %w", err)
+	}
+	return result, nil
+}
+
+// IsSecretKey 检查附加信息的键是否为敏感字段
+func IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range secretKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskSecrets 返回敏感字段打码后的附加信息副本
+func MaskSecrets(addition map[string]any) map[string]any {
+	masked := make(map[string]any, len(addition))
+	for k, v := range addition {
+		if s, ok := v.(string); ok && s != "" && IsSecretKey(k) {
+			masked[k] = MaskValue(s)
+			continue
+		}
+		masked[k] = v
+	}
+	return masked
+}
+
+// MaskValue 打码敏感值, 较长的值保留开头 4 个字符便于辨认
+func MaskValue(s string) string {
+	runes := []rune(s)
+	if len(runes) <= 8 {
+		return "****"
+	}
+	return string(runes[:4]) + "****"
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
+
+func TestProtocolSecretsMasked(t *testing.T) {
+	tests := []struct {
+		driver  string
+		secrets []string // 应打码的附加信息键
+		before  any
+		after   any
+	}{
+		{
+			driver:  "WebDav",
+			secrets: []string{"password"},
+			before:  model.WebDavAddition{Address: "https://nas", Username: "admin", Password: "webdav-old-password"},
+			after:   model.WebDavAddition{Address: "https://nas", Username: "admin", Password: "webdav-new-password"},
+		},
+		{
+			driver:  "S3",
+			secrets: []string{"secret_access_key", "session_token"},
+			before:  model.S3Addition{Bucket: "b", AccessKeyID: "k", SecretAccessKey: "s3-old-secret", SessionToken: "s3-old-session"},
+			after:   model.S3Addition{Bucket: "b", AccessKeyID: "k", SecretAccessKey: "s3-new-secret", SessionToken: "s3-new-session"},
+		},
+		{
+			driver:  "SFTP",
+			secrets: []string{"private_key", "password", "passphrase"},
+			before:  model.SFTPAddition{Address: "nas:22", PrivateKey: "sftp-old-key", Password: "sftp-old-password", Passphrase: "sftp-old-passphrase"},
+			after:   model.SFTPAddition{Address: "nas:22", PrivateKey: "sftp-new-key", Password: "sftp-new-password", Passphrase: "sftp-new-passphrase"},
+		},
+		{
+			driver:  "FTP",
+			secrets: []string{"password"},
+			before:  model.FTPAddition{Address: "nas:21", Password: "ftp-old-password"},
+			after:   model.FTPAddition{Address: "nas:21", Password: "ftp-new-password"},
+		},
+		{
+			driver:  "SMB",
+			secrets: []string{"password"},
+			before:  model.SMBAddition{Address: "nas:445", ShareName: "media", Password: "smb-old-password"},
+			after:   model.SMBAddition{Address: "nas:445", ShareName: "media", Password: "smb-new-password"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			for _, key := range tt.secrets {
+				if !IsSecretKey(key) {
+					t.Errorf("IsSecretKey(%q) = false", key)
+				}
+			}
+
+			before, after := storageWith(t, tt.driver, tt.before), storageWith(t, tt.driver, tt.after)
+			changed := make(map[string]bool)
+			for _, c := range DiffStorage(before, after) {
+				changed[strings.TrimPrefix(c.Field, "addition.")] = true
+				if strings.Contains(c.Before, "-old-") || strings.Contains(c.After, "-new-") {
+					t.Errorf("DiffStorage %s shows %q -> %q in clear text", c.Field, c.Before, c.After)
+				}
+			}
+			for _, key := range tt.secrets {
+				if !changed[key] {
+					t.Errorf("DiffStorage did not report a change of %s", key)
+				}
+			}
+
+			addition, err := DecodeAddition(before.Addition)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, v := range MaskSecrets(addition) {
+				if s, ok := v.(string); ok && strings.Contains(s, "-old-") {
+					t.Errorf("MaskSecrets left %s = %q in clear text", key, s)
+				}
+			}
+		})
+	}
+}
+
+// storageWith 返回附加信息为 addition 的存储
+func storageWith(t *testing.T, driver string, addition any) model.StorageItem {
+	t.Helper()
+	data, err := json.Marshal(addition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model.StorageItem{Id: 1, MountPath: "/NAS", Driver: driver, Addition: string(data)}
+}
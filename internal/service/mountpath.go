@@ -0,0 +1,225 @@
+// Package service 提供核心业务逻辑
+package service
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+	"github.com/yzbtdiy/openlist_batch/internal/provider"
+)
+
+// MountEntry 已生成挂载路径的分享条目
+type MountEntry struct {
+	Category  string // 分享文件中的原始分类
+	Name      string // 分享文件中的原始名称
+	Value     string // 分享链接或 OneDrive 信息
+	MountPath string
+}
+
+// Label 日志中显示的条目名称
+func (e MountEntry) Label() string {
+	return e.Category + "/" + e.Name
+}
+
+// MountPlan 挂载计划
+type MountPlan struct {
+	Entries []MountEntry
+	Present []MountEntry // 同一分享已挂载在生成的路径上
+	Skipped int          // 因路径冲突跳过
+	Invalid int          // 名称规范化后为空或无效
+}
+
+// PlanMounts 按 mount_path 规则为分享列表生成挂载路径
+//
+// 分类和名称先规范化再套用模板, 条目按分类和名称排序以保证序号稳定;
+// 路径上已有同一分享的存储时记入 Present, 与 existing 中其他存储或列表中
+// 前面的条目冲突时按 collision 处理, fail 模式下有任何冲突都返回错误
+func (s *BatchService) PlanMounts(p provider.Provider, shares config.ShareList, existing map[string]model.StorageItem) (*MountPlan, error) {
+	rules := s.cfg.MountPath
+	used := make(map[string]bool, len(existing))
+	for mountPath := range existing {
+		used[mountPath] = true
+	}
+
+	plan := &MountPlan{}
+	var conflicts []string
+
+	for _, category := range sortedKeys(shares) {
+		shareMap := shares[category]
+		for _, name := range sortedKeys(shareMap) {
+			entry := MountEntry{Category: category, Name: name, Value: shareMap[name]}
+
+			mountPath, err := renderMountPath(rules, p, category, name, entry.Value)
+			if err != nil {
+				log.Printf("[%s] %s %v", p.Name(), entry.Label(), err)
+				plan.Invalid++
+				continue
+			}
+
+			if item, ok := existing[mountPath]; ok && samePlannedShare(p, item, mountPath, entry.Value) {
+				entry.MountPath = mountPath
+				plan.Present = append(plan.Present, entry)
+				continue
+			}
+
+			if used[mountPath] {
+				switch rules.CollisionMode() {
+				case config.CollisionSkip:
+					log.Printf("[%s] %s 挂载路径 %s 已被占用, 跳过", p.Name(), entry.Label(), mountPath)
+					plan.Skipped++
+					continue
+				case config.CollisionFail:
+					conflicts = append(conflicts, entry.Label()+" -> "+mountPath)
+					continue
+				case config.CollisionSuffix:
+					base := mountPath
+					for i := 2; used[mountPath]; i++ {
+						mountPath = fmt.Sprintf("%s (%d)", base, i)
+					}
+					log.Printf("[%s] %s 挂载路径 %s 已被占用, 改为 %s", p.Name(), entry.Label(), base, mountPath)
+				}
+			}
+
+			used[mountPath] = true
+			entry.MountPath = mountPath
+			plan.Entries = append(plan.Entries, entry)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("[%s] 挂载路径冲突: %s", p.Name(), strings.Join(conflicts, ", "))
+	}
+	return plan, nil
+}
+
+// samePlannedShare 检查路径上已有的存储是否就是该条目对应的分享
+func samePlannedShare(p provider.Provider, item model.StorageItem, mountPath, value string) bool {
+	req, err := p.BuildRequest(mountPath, value)
+	if err != nil || req.Driver != item.Driver {
+		return false
+	}
+	want, ok := shareKeyOf(req.Driver, req.Addition)
+	if !ok {
+		return false
+	}
+	have, ok := shareKeyOf(item.Driver, item.Addition)
+	return ok && have == want
+}
+
+// renderMountPath 规范化分类和名称后套用模板
+func renderMountPath(rules config.MountPath, p provider.Provider, category, name, value string) (string, error) {
+	category, name = sanitizeSegment(rules, category), sanitizeSegment(rules, name)
+	if name == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("名称无效")
+	}
+	if category == "." || category == ".." {
+		return "", fmt.Errorf("分类无效")
+	}
+
+	r := strings.NewReplacer(
+		"{provider}", sanitizeSegment(rules, p.Name()),
+		"{driver}", entryDriver(p, value),
+		"{category}", category,
+		"{name}", name,
+	)
+	return path.Clean(r.Replace(rules.PathTemplate())), nil
+}
+
+// entryDriver 返回条目使用的驱动, 按条目选择驱动的提供商由条目内容决定
+func entryDriver(p provider.Provider, value string) string {
+	if _, ok := p.(provider.MultiDriverProvider); ok {
+		if req, err := p.BuildRequest("/", value); err == nil {
+			return req.Driver
+		}
+	}
+	return p.Driver()
+}
+
+// sanitizeSegment 规范化路径中的一段: 全角转半角, 替换斜杠, 合并空白并去掉首尾空白
+func sanitizeSegment(rules config.MountPath, s string) string {
+	if !rules.KeepFullWidth {
+		s = toHalfWidth(s)
+	}
+	rep := rules.SlashReplacement()
+	s = strings.NewReplacer("/", rep, "\\", rep).Replace(s)
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// toHalfWidth 把全角字母数字、符号和空格转为半角
+func toHalfWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '　':
+			return ' '
+		case r >= '！' && r <= '～':
+			return r - 0xfee0
+		}
+		return r
+	}, s)
+}
+
+// mountPathVarPattern 匹配模板中的变量
+var mountPathVarPattern = regexp.MustCompile(`\{(provider|driver|category|name)\}`)
+
+// mountPathMatcher 返回匹配由该分享列表按模板生成的挂载路径的正则,
+// 名称部分匹配任意一段, 用于找出分享列表管理范围内的存储
+func mountPathMatcher(rules config.MountPath, p provider.Provider, shares config.ShareList) (*regexp.Regexp, error) {
+	categories := make([]string, 0, len(shares))
+	for _, category := range sortedKeys(shares) {
+		categories = append(categories, regexp.QuoteMeta(sanitizeSegment(rules, category)))
+	}
+	if len(categories) == 0 {
+		return nil, fmt.Errorf("分享列表为空")
+	}
+
+	drivers := []string{regexp.QuoteMeta(p.Driver())}
+	if m, ok := p.(provider.MultiDriverProvider); ok {
+		drivers = drivers[:0]
+		for _, d := range m.Drivers() {
+			drivers = append(drivers, regexp.QuoteMeta(d))
+		}
+	}
+
+	vars := map[string]string{
+		"{provider}": regexp.QuoteMeta(sanitizeSegment(rules, p.Name())),
+		"{driver}":   "(?:" + strings.Join(drivers, "|") + ")",
+		"{category}": "(?:" + strings.Join(categories, "|") + ")",
+		"{name}":     "[^/]+",
+	}
+
+	tmpl := rules.PathTemplate()
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	for _, loc := range mountPathVarPattern.FindAllStringIndex(tmpl, -1) {
+		b.WriteString(regexp.QuoteMeta(tmpl[last:loc[0]]))
+		b.WriteString(vars[tmpl[loc[0]:loc[1]]])
+		last = loc[1]
+	}
+	b.WriteString(regexp.QuoteMeta(tmpl[last:]))
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// sortedKeys 返回排序后的键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
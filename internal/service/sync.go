@@ -0,0 +1,200 @@
+// Package service 提供核心业务逻辑
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+	"github.com/yzbtdiy/openlist_batch/internal/provider"
+)
+
+// SyncResult 同步结果统计
+type SyncResult struct {
+	Created   int
+	Updated   int
+	Unchanged int
+	Deleted   int
+	Skipped   int // 受保护、挂载路径冲突或重复分享而跳过
+	Failed    int
+	Verify    VerifyResult
+}
+
+// Merge 合并另一次同步的统计
+func (r *SyncResult) Merge(other SyncResult) {
+	r.Created += other.Created
+	r.Updated += other.Updated
+	r.Unchanged += other.Unchanged
+	r.Deleted += other.Deleted
+	r.Skipped += other.Skipped
+	r.Failed += other.Failed
+	r.Verify.Merge(other.Verify)
+}
+
+// SyncShares 让 OpenList 中该驱动的存储与分享列表一致
+//
+// 挂载路径按 mount_path 规则生成, 缺少的存储会创建 (重复分享按 duplicate 策略处理),
+// 附加信息不一致的会更新;
+// prune 为 true 时, 删除路径符合模板且属于列表中某个分类、但列表里已不存在的同驱动存储
+func (s *BatchService) SyncShares(p provider.Provider, shares config.ShareList, prune bool) (SyncResult, error) {
+	var result SyncResult
+
+	list, err := s.GetStorageList()
+	if err != nil {
+		return result, fmt.Errorf("获取存储列表失败: %w", err)
+	}
+
+	existing := make(map[string]model.StorageItem, len(list.Content))
+	for _, item := range list.Content {
+		existing[item.MountPath] = item
+	}
+
+	shares = s.resolveFolderPaths(p, shares)
+	plan, err := s.PlanMounts(p, shares, nil)
+	if err != nil {
+		return result, err
+	}
+	result.Skipped += plan.Skipped
+	result.Failed += plan.Invalid
+
+	dups := s.newDuplicateChecker(list.Content)
+	var created []string
+	replaced := make(map[int]bool)
+	wanted := make(map[string]bool)
+	for _, entry := range plan.Entries {
+		mountPath := entry.MountPath
+		wanted[mountPath] = true
+
+		req, err := p.BuildRequest(mountPath, entry.Value)
+		if err != nil {
+			log.Printf("[%s] %s 构建请求失败: %v", p.Name(), entry.Label(), err)
+			result.Failed++
+			continue
+		}
+		if err := checkMounts(p, entry.Value, list.Content); err != nil {
+			log.Printf("[%s] %s 引用的存储不存在: %v", p.Name(), entry.Label(), err)
+			result.Failed++
+			continue
+		}
+
+		item, ok := existing[mountPath]
+		if !ok {
+			add, replace := dups.check("["+p.Name()+"] "+entry.Label(), req)
+			if !add || (replace != nil && !s.canReplace(replace)) {
+				result.Skipped++
+				continue
+			}
+			if err := s.AddStorage(req); err != nil {
+				log.Printf("[%s] %s 添加失败: %v", p.Name(), entry.Label(), err)
+				result.Failed++
+				continue
+			}
+			log.Printf("[%s] %s 添加成功", p.Name(), entry.Label())
+			result.Created++
+			created = append(created, mountPath)
+			if replace != nil && s.replaceDuplicate(replace) {
+				replaced[replace.Id] = true
+				result.Deleted++
+			}
+			continue
+		}
+
+		if item.Driver != req.Driver {
+			log.Printf("[%s] %s 挂载路径已被 %s 存储占用", p.Name(), entry.Label(), item.Driver)
+			result.Failed++
+			continue
+		}
+		dups.markSeen("["+p.Name()+"] "+entry.Label(), req)
+
+		addition, changed, err := mergeAddition(item.Addition, req.Addition)
+		if err != nil {
+			log.Printf("[%s] %s 比较附加信息失败: %v", p.Name(), entry.Label(), err)
+			result.Failed++
+			continue
+		}
+		if !changed {
+			result.Unchanged++
+			continue
+		}
+		if s.IsProtected(mountPath) {
+			log.Printf("[%s] %s 受保护, 跳过更新", p.Name(), entry.Label())
+			result.Skipped++
+			continue
+		}
+
+		update := model.NewStorageUpdateRequest(item)
+		update.Addition = addition
+		if err := s.UpdateStorage(update); err != nil {
+			log.Printf("[%s] %s 更新失败: %v", p.Name(), entry.Label(), err)
+			result.Failed++
+			continue
+		}
+		log.Printf("[%s] %s 已更新", p.Name(), entry.Label())
+		result.Updated++
+	}
+	result.Verify = s.VerifyMounts(created)
+
+	if !prune {
+		return result, nil
+	}
+
+	managed, err := mountPathMatcher(s.cfg.MountPath, p, shares)
+	if err != nil {
+		return result, nil
+	}
+	for _, item := range list.Content {
+		if !provider.HasDriver(p, item.Driver) || wanted[item.MountPath] || replaced[item.Id] || !managed.MatchString(item.MountPath) {
+			continue
+		}
+		if s.IsProtected(item.MountPath) {
+			log.Printf("跳过受保护的存储 %d (%s)", item.Id, item.MountPath)
+			result.Skipped++
+			continue
+		}
+		if err := s.DeleteStorage(item.Id); err != nil {
+			log.Printf("删除存储 %d (%s) 失败: %v", item.Id, item.MountPath, err)
+			result.Failed++
+			continue
+		}
+		log.Printf("已删除存储 %d (%s)", item.Id, item.MountPath)
+		result.Deleted++
+	}
+
+	return result, nil
+}
+
+// mergeAddition 把期望的附加信息合并到已有附加信息上
+//
+// 只比较期望中出现的字段, 服务端补充的其他字段保持不变
+func mergeAddition(current, wanted string) (string, bool, error) {
+	var cur, want map[string]any
+	if err := json.Unmarshal([]byte(current), &cur); err != nil {
+		return "", false, err
+	}
+	if err := json.Unmarshal([]byte(wanted), &want); err != nil {
+		return "", false, err
+	}
+	if cur == nil {
+		cur = make(map[string]any)
+	}
+
+	changed := false
+	for k, v := range want {
+		if old, ok := cur[k]; !ok || !reflect.DeepEqual(old, v) {
+			cur[k] = v
+			changed = true
+		}
+	}
+	if !changed {
+		return current, false, nil
+	}
+
+	merged, err := marshalAddition(cur)
+	if err != nil {
+		return "", false, err
+	}
+	return merged, true, nil
+}
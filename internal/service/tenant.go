@@ -0,0 +1,106 @@
+// Package service 提供核心业务逻辑
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
+
+// TenantMount 更新应用凭据后的 OneDrive 存储
+type TenantMount struct {
+	Id        int
+	MountPath string
+	Status    string
+	Updated   bool // 本次提交了更新
+}
+
+// RotateTenantSecret 用 config.yaml 中租户的应用凭据更新该租户下的所有 OneDrive 存储
+//
+// 匹配附加信息中 tenant_id 相同、client_id 为 fromClientID (为空时为租户当前的 client_id)
+// 的存储, 替换 client_id 和 client_secret; 凭据没有变化且状态正常的存储不会重复提交,
+// 受保护的存储跳过. 提交后重新读取存储列表, 按挂载路径返回各存储的状态
+func (s *BatchService) RotateTenantSecret(tenant config.Tenant, fromClientID string) (RotateResult, []TenantMount, error) {
+	var result RotateResult
+	if fromClientID == "" {
+		fromClientID = tenant.ClientID
+	}
+
+	list, err := s.GetStorageList()
+	if err != nil {
+		return result, nil, fmt.Errorf("获取存储列表失败: %w", err)
+	}
+
+	updated := make(map[int]bool)
+	for _, item := range list.Content {
+		if item.Driver != "OnedriveAPP" {
+			continue
+		}
+		var addition model.OneDriveAppAddition
+		if err := json.Unmarshal([]byte(item.Addition), &addition); err != nil {
+			continue
+		}
+		if addition.TenantId != tenant.TenantID || addition.ClientId != fromClientID {
+			continue
+		}
+		updated[item.Id] = false
+
+		if s.IsProtected(item.MountPath) {
+			log.Printf("跳过受保护的存储 %s", item.MountPath)
+			result.Skipped++
+			continue
+		}
+
+		if addition.ClientId == tenant.ClientID && addition.ClientSecret == tenant.ClientSecret && item.Status == "work" {
+			result.Unchanged++
+			continue
+		}
+
+		wanted, err := marshalAddition(map[string]any{
+			"client_id":     tenant.ClientID,
+			"client_secret": tenant.ClientSecret,
+		})
+		if err != nil {
+			return result, nil, err
+		}
+		data, _, err := mergeAddition(item.Addition, wanted)
+		if err != nil {
+			log.Printf("合并附加信息失败 (%s): %v", item.MountPath, err)
+			result.Failed++
+			continue
+		}
+
+		req := model.NewStorageUpdateRequest(item)
+		req.Addition = data
+		if err := s.UpdateStorage(req); err != nil {
+			log.Printf("更新失败 (%s): %v", item.MountPath, err)
+			result.Failed++
+			continue
+		}
+		log.Printf("已更新 %s", item.MountPath)
+		updated[item.Id] = true
+		result.Updated++
+	}
+
+	if len(updated) == 0 {
+		return result, nil, nil
+	}
+
+	// 更新后 OpenList 会重新初始化存储, 重新读取以获得最新状态
+	list, err = s.GetStorageList()
+	if err != nil {
+		return result, nil, fmt.Errorf("获取存储列表失败: %w", err)
+	}
+	var mounts []TenantMount
+	for _, item := range list.Content {
+		if u, ok := updated[item.Id]; ok {
+			mounts = append(mounts, TenantMount{Id: item.Id, MountPath: item.MountPath, Status: item.Status, Updated: u})
+		}
+	}
+	sort.Slice(mounts, func(i, j int) bool { return mounts[i].MountPath < mounts[j].MountPath })
+	return result, mounts, nil
+}
@@ -0,0 +1,143 @@
+// Package service 提供核心业务逻辑
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/yzbtdiy/openlist_batch/internal/config"
+	"github.com/yzbtdiy/openlist_batch/internal/model"
+)
+
+// VerifyResult 新建存储的验证结果统计
+type VerifyResult struct {
+	OK       int
+	Empty    int // 根目录为空
+	Broken   int // 列出根目录失败
+	Disabled int
+	Deleted  int
+}
+
+// Merge 合并另一次验证的统计
+func (r *VerifyResult) Merge(other VerifyResult) {
+	r.OK += other.OK
+	r.Empty += other.Empty
+	r.Broken += other.Broken
+	r.Disabled += other.Disabled
+	r.Deleted += other.Deleted
+}
+
+// ListDir 列出 OpenList 中的目录, refresh 为 true 时不使用缓存
+func (s *BatchService) ListDir(dir string, refresh bool) (*model.FsListResponse, error) {
+	data, err := json.Marshal(model.FsListRequest{Path: dir, Page: 1, Refresh: refresh})
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	resp, err := s.client.Post(FsListEndpoint, data)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 200 {
+		return nil, fmt.Errorf("%s", resp.Message)
+	}
+
+	content, err := json.Marshal(resp.Data)
+	if err != nil {
+		return nil, fmt.Errorf("序列化目录列表失败: %w", err)
+	}
+	var list model.FsListResponse
+	if err := json.Unmarshal(content, &list); err != nil {
+		return nil, fmt.Errorf("解析目录列表失败: %w", err)
+	}
+	return &list, nil
+}
+
+// VerifyMounts 按 verify 策略验证新建的存储
+//
+// 并发列出每个挂载路径的根目录, 为空或失败的挂载记入结果;
+// disable 和 delete 策略下随后禁用或删除这些存储, 受保护的存储只报告
+func (s *BatchService) VerifyMounts(mountPaths []string) VerifyResult {
+	var result VerifyResult
+	policy := s.cfg.VerifyPolicy()
+	if policy == config.VerifyOff || len(mountPaths) == 0 {
+		return result
+	}
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		bad []string
+	)
+	for _, mountPath := range mountPaths {
+		wg.Add(1)
+		go func(mountPath string) {
+			defer wg.Done()
+
+			list, err := s.ListDir(mountPath, true)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				log.Printf("验证 %s 失败: %v", mountPath, err)
+				result.Broken++
+			case list.Total == 0 && len(list.Content) == 0:
+				log.Printf("验证 %s: 根目录为空", mountPath)
+				result.Empty++
+			default:
+				result.OK++
+				return
+			}
+			bad = append(bad, mountPath)
+		}(mountPath)
+	}
+	wg.Wait()
+
+	if len(bad) == 0 || policy == config.VerifyReport {
+		return result
+	}
+
+	list, err := s.GetStorageList()
+	if err != nil {
+		log.Printf("获取存储列表失败, 无法处理验证未通过的存储: %v", err)
+		return result
+	}
+	byPath := make(map[string]model.StorageItem, len(list.Content))
+	for _, item := range list.Content {
+		byPath[item.MountPath] = item
+	}
+
+	sort.Strings(bad)
+	for _, mountPath := range bad {
+		item, ok := byPath[mountPath]
+		if !ok {
+			continue
+		}
+		if s.IsProtected(mountPath) {
+			log.Printf("存储 %d (%s) 受保护, 只报告不处理", item.Id, mountPath)
+			continue
+		}
+
+		if policy == config.VerifyDelete {
+			if err := s.DeleteStorage(item.Id); err != nil {
+				log.Printf("删除存储 %d (%s) 失败: %v", item.Id, mountPath, err)
+				continue
+			}
+			log.Printf("已删除验证未通过的存储 %d (%s)", item.Id, mountPath)
+			result.Deleted++
+			continue
+		}
+
+		item.Disabled = true
+		if err := s.SaveStorage(item); err != nil {
+			log.Printf("禁用存储 %d (%s) 失败: %v", item.Id, mountPath, err)
+			continue
+		}
+		log.Printf("已禁用验证未通过的存储 %d (%s)", item.Id, mountPath)
+		result.Disabled++
+	}
+	return result
+}
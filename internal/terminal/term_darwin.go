@@ -0,0 +1,9 @@
+// Package terminal 提供交互界面所需的终端控制, 只依赖标准库
+package terminal
+
+import "syscall"
+
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
@@ -0,0 +1,21 @@
+//go:build !linux && !darwin
+
+// Package terminal 提供交互界面所需的终端控制, 只依赖标准库
+package terminal
+
+import "errors"
+
+// errUnsupported 当前平台不支持原始模式
+var errUnsupported = errors.New("当前平台不支持交互界面, 请在 Linux 或 macOS 终端中运行")
+
+func isTerminal(fd int) bool {
+	return true
+}
+
+func makeRaw(fd int) (func() error, error) {
+	return nil, errUnsupported
+}
+
+func getSize(fd int) (width, height int, err error) {
+	return 0, 0, errUnsupported
+}
@@ -0,0 +1,240 @@
+// Package terminal 提供交互界面所需的终端控制, 只依赖标准库
+//
+// 通过 ANSI 转义序列绘制界面, 可在 SSH 会话和无图形界面的 Linux 上使用
+package terminal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrNotTerminal 标准输入或输出不是终端
+var ErrNotTerminal = errors.New("标准输入输出不是终端, 交互界面需要在终端中运行")
+
+// Key 按键类型
+type Key int
+
+// 按键类型
+const (
+	KeyRune Key = iota
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyEnter
+	KeyTab
+	KeyBackspace
+	KeyEsc
+	KeyCtrlC
+)
+
+// Event 一次按键
+type Event struct {
+	Key  Key
+	Rune rune // Key 为 KeyRune 时的字符
+}
+
+// ANSI 颜色与样式
+const (
+	Reset   = "\x1b[0m"
+	Bold    = "\x1b[1m"
+	Reverse = "\x1b[7m"
+	Red     = "\x1b[31m"
+	Green   = "\x1b[32m"
+	Yellow  = "\x1b[33m"
+	Cyan    = "\x1b[36m"
+	Gray    = "\x1b[90m"
+)
+
+// Terminal 原始模式下的终端
+type Terminal struct {
+	in      *os.File
+	out     *bufio.Writer
+	restore func() error
+	pending []byte
+}
+
+// Open 把终端切换到原始模式并进入备用屏幕, 使用完毕后必须调用 Close
+func Open() (*Terminal, error) {
+	if !isTerminal(int(os.Stdin.Fd())) || !isTerminal(int(os.Stdout.Fd())) {
+		return nil, ErrNotTerminal
+	}
+
+	restore, err := makeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("设置终端模式失败: %w", err)
+	}
+
+	t := &Terminal{in: os.Stdin, out: bufio.NewWriter(os.Stdout), restore: restore}
+	// 备用屏幕, 隐藏光标
+	t.out.WriteString("\x1b[?1049h\x1b[?25l")
+	return t, t.out.Flush()
+}
+
+// Close 恢复终端
+func (t *Terminal) Close() error {
+	t.out.WriteString(Reset + "\x1b[?25h\x1b[?1049l")
+	t.out.Flush()
+	return t.restore()
+}
+
+// Size 返回终端的列数和行数, 获取失败时返回 80x24
+func (t *Terminal) Size() (width, height int) {
+	width, height, err := getSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// Draw 清屏并逐行绘制, 超出宽度的行被截断
+func (t *Terminal) Draw(lines []string) error {
+	width, height := t.Size()
+	t.out.WriteString("\x1b[H\x1b[2J")
+	for i, line := range lines {
+		if i >= height {
+			break
+		}
+		if i > 0 {
+			t.out.WriteString("\r\n")
+		}
+		t.out.WriteString(Truncate(line, width))
+		t.out.WriteString(Reset)
+	}
+	return t.out.Flush()
+}
+
+// ReadEvent 读取一次按键
+func (t *Terminal) ReadEvent() (Event, error) {
+	if len(t.pending) == 0 {
+		buf := make([]byte, 64)
+		n, err := t.in.Read(buf)
+		if err != nil {
+			return Event{}, err
+		}
+		t.pending = buf[:n]
+	}
+
+	ev, size := parseEvent(t.pending)
+	t.pending = t.pending[size:]
+	return ev, nil
+}
+
+// parseEvent 从输入中解析第一个按键, 返回按键和消耗的字节数
+func parseEvent(b []byte) (Event, int) {
+	switch b[0] {
+	case '\r', '\n':
+		return Event{Key: KeyEnter}, 1
+	case '\t':
+		return Event{Key: KeyTab}, 1
+	case 0x7f, 0x08:
+		return Event{Key: KeyBackspace}, 1
+	case 0x03:
+		return Event{Key: KeyCtrlC}, 1
+	case 0x1b:
+		return parseEscape(b)
+	}
+
+	r, size := utf8.DecodeRune(b)
+	return Event{Key: KeyRune, Rune: r}, size
+}
+
+// escapeKeys CSI 序列对应的按键
+var escapeKeys = map[string]Key{
+	"A": KeyUp, "B": KeyDown, "C": KeyRight, "D": KeyLeft,
+	"H": KeyHome, "F": KeyEnd, "1~": KeyHome, "4~": KeyEnd,
+	"5~": KeyPageUp, "6~": KeyPageDown,
+}
+
+// parseEscape 解析以 ESC 开头的序列, 无法识别时视为单独的 ESC
+func parseEscape(b []byte) (Event, int) {
+	if len(b) < 3 || (b[1] != '[' && b[1] != 'O') {
+		return Event{Key: KeyEsc}, 1
+	}
+	// CSI 序列以 0x40-0x7e 之间的字节结束
+	for i := 2; i < len(b); i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			if key, ok := escapeKeys[string(b[2:i+1])]; ok {
+				return Event{Key: key}, i + 1
+			}
+			return Event{Key: KeyEsc}, i + 1
+		}
+	}
+	return Event{Key: KeyEsc}, len(b)
+}
+
+// Width 返回字符串的显示宽度, 忽略 ANSI 转义序列, 中日韩字符计为 2
+func Width(s string) int {
+	width := 0
+	inEscape := false
+	for _, r := range s {
+		switch {
+		case inEscape:
+			inEscape = r < 0x40 || r > 0x7e || r == '['
+		case r == 0x1b:
+			inEscape = true
+		default:
+			width += runeWidth(r)
+		}
+	}
+	return width
+}
+
+// Truncate 把字符串截断到指定显示宽度, 保留 ANSI 转义序列
+func Truncate(s string, width int) string {
+	if Width(s) <= width {
+		return s
+	}
+
+	var b strings.Builder
+	used := 0
+	inEscape := false
+	for _, r := range s {
+		switch {
+		case inEscape:
+			inEscape = r < 0x40 || r > 0x7e || r == '['
+			b.WriteRune(r)
+		case r == 0x1b:
+			inEscape = true
+			b.WriteRune(r)
+		default:
+			w := runeWidth(r)
+			if used+w > width {
+				return b.String()
+			}
+			used += w
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Pad 在字符串右侧补空格到指定显示宽度
+func Pad(s string, width int) string {
+	if w := Width(s); w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	return s
+}
+
+// runeWidth 返回字符的显示宽度
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20:
+		return 0
+	case unicode.Is(unicode.Han, r), unicode.Is(unicode.Hangul, r),
+		unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r),
+		r >= 0x3000 && r <= 0x303f, r >= 0xff00 && r <= 0xff60, r >= 0xffe0 && r <= 0xffe6:
+		return 2
+	}
+	return 1
+}